@@ -0,0 +1,142 @@
+package dynamodbstore
+
+import (
+    "context"
+    "crypto/sha256"
+    "fmt"
+    "math/rand/v2"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxBatchGetKeys is the most keys DynamoDB accepts in one BatchGetItem.
+const maxBatchGetKeys = 100
+
+// Unprocessed batch items are retried up to maxBatchRetries times, waiting
+// an exponentially growing, jittered delay between attempts.
+const (
+    maxBatchRetries = 8
+    batchRetryBase  = 50 * time.Millisecond
+    batchRetryMax   = 5 * time.Second
+)
+
+// BatchGet reads the items with keys using strongly consistent reads,
+// batching them into BatchGetItem requests of up to 100 keys. items and
+// found follow the order of keys: found[i] tells whether keys[i] exists and
+// items[i] is its zero value when it does not. Keys may repeat.
+func (s *Store[T]) BatchGet(ctx context.Context, keys []Key) (items []T, found []bool, err error) {
+    items = make([]T, len(keys))
+    found = make([]bool, len(keys))
+
+    // BatchGetItem rejects repeated keys, so each one is read once and
+    // copied to every position asking for it.
+    positions := make(map[string][]int, len(keys))
+    var distinct []map[string]types.AttributeValue
+    for i, key := range keys {
+        dynamoKey, err := s.key(key)
+        if err != nil {
+            return nil, nil, fmt.Errorf("key %d: %w", i, err)
+        }
+        id := keyIdentity(dynamoKey)
+        if _, seen := positions[id]; !seen {
+            distinct = append(distinct, dynamoKey)
+        }
+        positions[id] = append(positions[id], i)
+    }
+
+    for start := 0; start < len(distinct); start += maxBatchGetKeys {
+        records, err := s.batchGet(ctx, distinct[start:min(start+maxBatchGetKeys, len(distinct))])
+        if err != nil {
+            return nil, nil, err
+        }
+
+        for _, record := range records {
+            // Identify the record before decoding changes its time keys
+            id := keyIdentity(s.recordKey(record))
+            var item T
+            if err := s.times.unmarshalRecord(record, &item); err != nil {
+                return nil, nil, fmt.Errorf("failed to decode record: %w", err)
+            }
+            for _, i := range positions[id] {
+                items[i], found[i] = item, true
+            }
+        }
+    }
+    return items, found, nil
+}
+
+// batchGet runs one BatchGetItem, retrying its unprocessed keys.
+func (s *Store[T]) batchGet(ctx context.Context, keys []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+    var records []map[string]types.AttributeValue
+    request := map[string]types.KeysAndAttributes{
+        s.kind: {Keys: keys, ConsistentRead: aws.Bool(true)},
+    }
+
+    for attempt := 0; ; attempt++ {
+        output, err := s.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+        if err != nil {
+            return nil, fmt.Errorf("failed to get records: %w", err)
+        }
+        records = append(records, output.Responses[s.kind]...)
+
+        request = output.UnprocessedKeys
+        unprocessed := len(request[s.kind].Keys)
+        if unprocessed == 0 {
+            return records, nil
+        }
+        if attempt == maxBatchRetries {
+            return nil, fmt.Errorf("failed to get records: %d keys still unprocessed after %d retries", unprocessed, maxBatchRetries)
+        }
+        if err := s.wait(ctx, attempt); err != nil {
+            return nil, err
+        }
+    }
+}
+
+// recordKey picks the table keys out of a stored item.
+func (s *Store[T]) recordKey(record map[string]types.AttributeValue) map[string]types.AttributeValue {
+    key := map[string]types.AttributeValue{s.partitionKey: record[s.partitionKey]}
+    if s.sortKey != "" {
+        key[s.sortKey] = record[s.sortKey]
+    }
+    return key
+}
+
+// wait sleeps before retry attempt of unprocessed batch items.
+func (s *Store[T]) wait(ctx context.Context, attempt int) error {
+    delay := retryDelay
+    if s.backoff != nil {
+        delay = s.backoff
+    }
+
+    timer := time.NewTimer(delay(attempt))
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-timer.C:
+        return nil
+    }
+}
+
+// retryDelay is exponential backoff with full jitter.
+func retryDelay(attempt int) time.Duration {
+    delay := batchRetryMax
+    if attempt < 16 {
+        delay = min(batchRetryBase<<attempt, batchRetryMax)
+    }
+    return rand.N(delay)
+}
+
+// keyIdentity is a comparable form of an item key.
+func keyIdentity(key map[string]types.AttributeValue) string {
+    h := sha256.New()
+    for _, name := range sortedKeys(key) {
+        writeString(h, name)
+        writeValue(h, key[name])
+    }
+    return string(h.Sum(nil))
+}
@@ -0,0 +1,99 @@
+package dynamodbstore
+
+import (
+    "context"
+    "fmt"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func newBundleStore(t *testing.T, mockClient *MockDynamoDBClient) *Store[Bundle] {
+    store, err := NewStore[Bundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+    store.backoff = func(int) time.Duration { return 0 }
+    return store
+}
+
+func TestBatchGetRetriesUnprocessedKeys(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("BatchGetItem", ctx, mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+        Responses: map[string][]map[string]types.AttributeValue{"BundlesTable": {bundleItem("a")}},
+        UnprocessedKeys: map[string]types.KeysAndAttributes{
+            "BundlesTable": {Keys: []map[string]types.AttributeValue{bundleItem("b")}},
+        },
+    }, nil).Once()
+    mockClient.On("BatchGetItem", ctx, mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+        Responses: map[string][]map[string]types.AttributeValue{"BundlesTable": {bundleItem("b")}},
+    }, nil).Once()
+
+    store := newBundleStore(t, mockClient)
+    items, found, err := store.BatchGet(ctx, []Key{{Partition: "b"}, {Partition: "missing"}, {Partition: "a"}, {Partition: "b"}})
+    assert.NoError(t, err)
+    assert.Equal(t, []Bundle{{ID: "b"}, {}, {ID: "a"}, {ID: "b"}}, items)
+    assert.Equal(t, []bool{true, false, true, true}, found)
+    mockClient.AssertExpectations(t)
+
+    // The repeated key is requested once
+    first := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.BatchGetItemInput)
+    assert.Len(t, first.RequestItems["BundlesTable"].Keys, 3)
+    retry := mockClient.Calls[1].Arguments.Get(1).(*dynamodb.BatchGetItemInput)
+    assert.Equal(t, []map[string]types.AttributeValue{bundleItem("b")}, retry.RequestItems["BundlesTable"].Keys)
+}
+
+func TestBatchGetChunksKeys(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("BatchGetItem", ctx, mock.Anything).Return(&dynamodb.BatchGetItemOutput{}, nil)
+
+    keys := make([]Key, 250)
+    for i := range keys {
+        keys[i] = Key{Partition: fmt.Sprintf("bundle-%d", i)}
+    }
+    _, found, err := newBundleStore(t, mockClient).BatchGet(ctx, keys)
+    assert.NoError(t, err)
+    assert.NotContains(t, found, true)
+
+    var sizes []int
+    for _, call := range mockClient.Calls {
+        input := call.Arguments.Get(1).(*dynamodb.BatchGetItemInput)
+        sizes = append(sizes, len(input.RequestItems["BundlesTable"].Keys))
+        assert.True(t, *input.RequestItems["BundlesTable"].ConsistentRead)
+    }
+    assert.Equal(t, []int{100, 100, 50}, sizes)
+}
+
+func TestBatchGetGivesUpOnUnprocessedKeys(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("BatchGetItem", ctx, mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+        UnprocessedKeys: map[string]types.KeysAndAttributes{
+            "BundlesTable": {Keys: []map[string]types.AttributeValue{bundleItem("a")}},
+        },
+    }, nil)
+
+    _, _, err := newBundleStore(t, mockClient).BatchGet(ctx, []Key{{Partition: "a"}})
+    assert.Error(t, err)
+    mockClient.AssertNumberOfCalls(t, "BatchGetItem", maxBatchRetries+1)
+}
+
+func TestBatchGetStopsWaitingWhenCanceled(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("BatchGetItem", ctx, mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+        UnprocessedKeys: map[string]types.KeysAndAttributes{
+            "BundlesTable": {Keys: []map[string]types.AttributeValue{bundleItem("a")}},
+        },
+    }, nil).Run(func(mock.Arguments) { cancel() })
+
+    store, err := NewStore[Bundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+    _, _, err = store.BatchGet(ctx, []Key{{Partition: "a"}})
+    assert.ErrorIs(t, err, context.Canceled)
+    mockClient.AssertNumberOfCalls(t, "BatchGetItem", 1)
+}
@@ -0,0 +1,201 @@
+package dynamodbstore
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "sync"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxBatchWriteItems is the most writes DynamoDB accepts in one
+// BatchWriteItem.
+const maxBatchWriteItems = 25
+
+// ItemFailure is an item a batch write could not store. Index is its
+// position in the input slice, or the order it was received from the
+// channel.
+type ItemFailure struct {
+    Index int
+    Err   error
+}
+
+// BatchWriteError lists every item a batch write could not store, by Index.
+type BatchWriteError struct {
+    Failures []ItemFailure
+}
+
+func (e *BatchWriteError) Error() string {
+    first := e.Failures[0]
+    return fmt.Sprintf("failed to write %d records, first at item %d: %v", len(e.Failures), first.Index, first.Err)
+}
+
+func (e *BatchWriteError) Unwrap() []error {
+    errs := make([]error, len(e.Failures))
+    for i, failure := range e.Failures {
+        errs[i] = failure.Err
+    }
+    return errs
+}
+
+// pendingWrite is an encoded item waiting in a batch.
+type pendingWrite struct {
+    index  int
+    record map[string]types.AttributeValue
+}
+
+// BatchPut writes items with BatchWriteItem requests of up to 25 items,
+// running at most workers requests at once. See BatchPutStream.
+func (s *Store[T]) BatchPut(ctx context.Context, items []T, workers int) error {
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    stream := make(chan T)
+    go func() {
+        defer close(stream)
+        for _, item := range items {
+            select {
+            case stream <- item:
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+    return s.BatchPutStream(ctx, stream, workers)
+}
+
+// BatchPutStream writes the items received from items until it is closed,
+// with BatchWriteItem requests of up to 25 items and at most workers requests
+// at once. Unprocessed items are retried with backoff; items that still
+// cannot be written are reported in a *BatchWriteError while the rest are
+// stored. Batch writes replace items unconditionally, so they are refused on
+// a versioned store, and when items repeat a key which write wins is
+// unspecified.
+func (s *Store[T]) BatchPutStream(ctx context.Context, items <-chan T, workers int) error {
+    if workers < 1 {
+        return fmt.Errorf("workers must be at least 1, got %d", workers)
+    }
+    if s.version != nil {
+        var item T
+        return fmt.Errorf("batch writes cannot check the version of %T; use Put", item)
+    }
+
+    var mu sync.Mutex
+    var failures []ItemFailure
+    fail := func(failed ...ItemFailure) {
+        mu.Lock()
+        defer mu.Unlock()
+        failures = append(failures, failed...)
+    }
+
+    chunks := make(chan []pendingWrite)
+    var wg sync.WaitGroup
+    for range workers {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for chunk := range chunks {
+                fail(s.batchWrite(ctx, chunk)...)
+            }
+        }()
+    }
+
+    err := s.chunkWrites(ctx, items, chunks, fail)
+    close(chunks)
+    wg.Wait()
+    if err != nil {
+        return err
+    }
+
+    if len(failures) == 0 {
+        return nil
+    }
+    sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+    return &BatchWriteError{Failures: failures}
+}
+
+// chunkWrites encodes items and groups them into batches. A batch is sent
+// early when an item repeats one of its keys, which BatchWriteItem rejects.
+func (s *Store[T]) chunkWrites(ctx context.Context, items <-chan T, chunks chan<- []pendingWrite, fail func(...ItemFailure)) error {
+    var chunk []pendingWrite
+    keys := make(map[string]bool)
+    flush := func() error {
+        if len(chunk) == 0 {
+            return nil
+        }
+        select {
+        case chunks <- chunk:
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+        chunk, keys = nil, make(map[string]bool)
+        return nil
+    }
+
+    for index := 0; ; index++ {
+        var item T
+        var ok bool
+        select {
+        case item, ok = <-items:
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+        if !ok {
+            return flush()
+        }
+
+        record, err := s.marshalItem(item)
+        if err != nil {
+            fail(ItemFailure{Index: index, Err: err})
+            continue
+        }
+        id := keyIdentity(s.recordKey(record))
+        if keys[id] || len(chunk) == maxBatchWriteItems {
+            if err := flush(); err != nil {
+                return err
+            }
+        }
+        chunk = append(chunk, pendingWrite{index: index, record: record})
+        keys[id] = true
+    }
+}
+
+// batchWrite runs one BatchWriteItem, retrying its unprocessed items, and
+// returns the items it could not write.
+func (s *Store[T]) batchWrite(ctx context.Context, chunk []pendingWrite) []ItemFailure {
+    indexes := make(map[string]int, len(chunk))
+    requests := make([]types.WriteRequest, len(chunk))
+    for i, write := range chunk {
+        indexes[keyIdentity(s.recordKey(write.record))] = write.index
+        requests[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: write.record}}
+    }
+    failAll := func(err error) []ItemFailure {
+        failures := make([]ItemFailure, len(requests))
+        for i, request := range requests {
+            failures[i] = ItemFailure{Index: indexes[keyIdentity(s.recordKey(request.PutRequest.Item))], Err: err}
+        }
+        return failures
+    }
+
+    for attempt := 0; ; attempt++ {
+        output, err := s.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+            RequestItems: map[string][]types.WriteRequest{s.kind: requests},
+        })
+        if err != nil {
+            return failAll(fmt.Errorf("failed to write records: %w", err))
+        }
+
+        requests = output.UnprocessedItems[s.kind]
+        if len(requests) == 0 {
+            return nil
+        }
+        if attempt == maxBatchRetries {
+            return failAll(fmt.Errorf("failed to write record: still unprocessed after %d retries", maxBatchRetries))
+        }
+        if err := s.wait(ctx, attempt); err != nil {
+            return failAll(err)
+        }
+    }
+}
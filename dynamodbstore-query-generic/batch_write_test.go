@@ -0,0 +1,88 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func bundleWrites(input *dynamodb.BatchWriteItemInput) []string {
+    var ids []string
+    for _, request := range input.RequestItems["BundlesTable"] {
+        ids = append(ids, request.PutRequest.Item["ID"].(*types.AttributeValueMemberS).Value)
+    }
+    return ids
+}
+
+func TestBatchPutChunksItems(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("BatchWriteItem", mock.Anything, mock.Anything).Return(&dynamodb.BatchWriteItemOutput{}, nil)
+
+    bundles := make([]Bundle, 60)
+    for i := range bundles {
+        bundles[i] = Bundle{ID: fmt.Sprintf("bundle-%d", i)}
+    }
+    assert.NoError(t, newBundleStore(t, mockClient).BatchPut(ctx, bundles, 3))
+
+    var sizes []int
+    for _, call := range mockClient.Calls {
+        sizes = append(sizes, len(bundleWrites(call.Arguments.Get(1).(*dynamodb.BatchWriteItemInput))))
+    }
+    assert.ElementsMatch(t, []int{25, 25, 10}, sizes)
+}
+
+func TestBatchPutReportsFailedItems(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("BatchWriteItem", mock.Anything, mock.Anything).Return(&dynamodb.BatchWriteItemOutput{
+        UnprocessedItems: map[string][]types.WriteRequest{
+            "BundlesTable": {{PutRequest: &types.PutRequest{Item: bundleItem("b")}}},
+        },
+    }, nil).Once()
+    throttled := errors.New("throttled")
+    mockClient.On("BatchWriteItem", mock.Anything, mock.Anything).Return((*dynamodb.BatchWriteItemOutput)(nil), throttled).Once()
+
+    err := newBundleStore(t, mockClient).BatchPut(ctx, []Bundle{{ID: "a"}, {ID: "b"}, {ID: "c"}}, 1)
+    var batchErr *BatchWriteError
+    assert.ErrorAs(t, err, &batchErr)
+    assert.Len(t, batchErr.Failures, 1)
+    assert.Equal(t, 1, batchErr.Failures[0].Index)
+    assert.ErrorIs(t, err, throttled)
+
+    retry := mockClient.Calls[1].Arguments.Get(1).(*dynamodb.BatchWriteItemInput)
+    assert.Equal(t, []string{"b"}, bundleWrites(retry))
+}
+
+func TestBatchPutStreamSplitsRepeatedKeys(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("BatchWriteItem", mock.Anything, mock.Anything).Return(&dynamodb.BatchWriteItemOutput{}, nil)
+
+    items := make(chan Bundle, 3)
+    items <- Bundle{ID: "a"}
+    items <- Bundle{ID: "b"}
+    items <- Bundle{ID: "a", Name: "again"}
+    close(items)
+
+    assert.NoError(t, newBundleStore(t, mockClient).BatchPutStream(ctx, items, 1))
+    assert.Equal(t, []string{"a", "b"}, bundleWrites(mockClient.Calls[0].Arguments.Get(1).(*dynamodb.BatchWriteItemInput)))
+    assert.Equal(t, []string{"a"}, bundleWrites(mockClient.Calls[1].Arguments.Get(1).(*dynamodb.BatchWriteItemInput)))
+}
+
+func TestBatchPutRejectsVersionedStores(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    store, err := NewStore[VersionedBundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+
+    assert.Error(t, store.BatchPut(ctx, []VersionedBundle{{ID: "a"}}, 1))
+    assert.Error(t, newBundleStore(t, mockClient).BatchPut(ctx, []Bundle{{ID: "a"}}, 0))
+    mockClient.AssertNotCalled(t, "BatchWriteItem", mock.Anything, mock.Anything)
+}
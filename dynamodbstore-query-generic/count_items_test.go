@@ -0,0 +1,51 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestCountItemsUsesSelectCount(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Count: 2, ScannedCount: 10, LastEvaluatedKey: map[string]types.AttributeValue{"Token": &types.AttributeValueMemberS{Value: "a"}}}, nil).Once()
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Count: 3, ScannedCount: 4}, nil).Once()
+
+    filters := []Filter{{Name: "Token", Op: EqualTo, Value: "token123"}, {Name: "ExpiresAt", Op: LessThan, Value: "2024-01-01T00:00:00Z"}}
+    count, err := CountItems(ctx, "JoinTokensTable", mockClient, "Token", "", filters)
+    assert.NoError(t, err)
+    assert.Equal(t, ItemCount{Count: 5, ScannedCount: 14}, count)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Equal(t, types.SelectCount, input.Select)
+    assert.Nil(t, input.ProjectionExpression)
+    assert.NotNil(t, input.FilterExpression)
+}
+
+func TestCountItemsVerifiesLocally(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{
+        Items: []map[string]types.AttributeValue{
+            selectorItem("spiffe://example.org/a", "unix:uid:0"),
+            selectorItem("spiffe://example.org/b", "unix:uid:0", "k8s:ns:default"),
+        },
+        Count:        2,
+        ScannedCount: 5,
+    }, nil)
+
+    filters := []Filter{{Name: "SpiffeID", Op: EqualTo, Value: "spiffe://example.org/a"}, {Name: "Selectors", Op: MatchSubset, Value: []string{"unix:uid:0", "unix:gid:0"}}}
+    count, err := CountItems(ctx, "EntriesTable", mockClient, "SpiffeID", "", filters)
+    assert.NoError(t, err)
+    assert.Equal(t, ItemCount{Count: 1, ScannedCount: 5}, count)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Empty(t, input.Select)
+    assert.ElementsMatch(t, []string{"SpiffeID", "Selectors"}, attributeNames(input))
+    assert.Equal(t, "Selectors", input.ExpressionAttributeNames[*input.ProjectionExpression])
+}
@@ -2,12 +2,13 @@ package dynamodbstore
 
 import (
     "context"
+    "errors"
     "fmt"
+    "time"
 
     "github.com/aws/aws-sdk-go-v2/aws"
     "github.com/aws/aws-sdk-go-v2/service/dynamodb"
     "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
-    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
     "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
@@ -15,10 +16,15 @@ type dynamoQueryClient interface {
     Query(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
 }
 
+// Filter is either a leaf comparison (Name, Op, Value) or, when Combinator
+// is set, a group combining Filters with And, Or or Not.
 type Filter struct {
     Name  string
     Op    MatchBehavior
     Value interface{}
+
+    Combinator Combinator
+    Filters    []Filter
 }
 
 type MatchBehavior int
@@ -31,70 +37,145 @@ const (
     LessThan
     GreaterThan
     EqualTo
+    LessOrEqual
+    GreaterOrEqual
+    Between    // Value is a Range
+    BeginsWith // Value is a string prefix
+    NotEqual
+    In                 // Value is a slice of candidates
+    AttributeExists    // Value is ignored
+    AttributeNotExists // Value is ignored
+    AttributeType      // Value is an expression.DynamoDBAttributeType
+    Size               // Value is a SizeComparison
 )
 
+// Range holds the inclusive bounds of a Between filter.
+type Range struct {
+    Lower interface{}
+    Upper interface{}
+}
+
+// SizeComparison compares the size of an attribute in a Size filter. Op is
+// EqualTo, NotEqual, one of the ordering comparisons, or Between with a
+// Range Value.
+type SizeComparison struct {
+    Op    MatchBehavior
+    Value interface{}
+}
+
+// Pagination carries the opaque page tokens. When Signer is set, NextToken
+// is signed and Token must carry a valid signature. When Fill is set, Limit
+// counts matching items rather than evaluated ones: ListItems keeps reading
+// until it has Limit items or the table ends, and NextToken resumes right
+// after the last item returned. A cut page resumes from the key attributes
+// of its last item; ListItems reads them even when the projection leaves
+// them out and drops them from the items it returns.
 type Pagination struct {
     Token    string
     Limit    int
     NextToken string
+
+    Signer *TokenSigner
+    Fill   bool
 }
 
-func ListItems[T any](
-    ctx context.Context,
-    kind string,
-    dynamoClient dynamoQueryClient, 
-    partitionKey string,
-    filters []Filter,
-    pagination *Pagination,
-    projection []string,
-) ([]T, *Pagination, error) {
+// queryRequest is a Query built from the filters and projection, with what
+// is needed to post-process the pages it returns.
+type queryRequest struct {
+    input            *dynamodb.QueryInput
+    fingerprint      string
+    remaining        []Filter
+    verify           bool
+    verificationOnly []string
+}
 
-    var keyCondition expression.KeyConditionBuilder
-    var filterExpression expression.ConditionBuilder
-    hasFilters := false
+// newQueryRequest splits filters into the key condition and the filter
+// expression. keepKeys projects the keys, and the table keys of an index
+// query, so a page can be cut at any item.
+func newQueryRequest(kind, partitionKey, sortKey string, filters []Filter, projection []string, keepKeys bool, settings options) (*queryRequest, error) {
+    var keyCondition, sortCondition expression.KeyConditionBuilder
+    hasSortCondition := false
+    var remaining []Filter
 
     for _, filter := range filters {
-        field := expression.Name(filter.Name)
-        
-        if filter.Name == partitionKey && filter.Op == EqualTo {
+        if filter.Combinator == 0 && filter.Name == partitionKey && filter.Op == EqualTo {
             keyCondition = expression.Key(filter.Name).Equal(expression.Value(filter.Value))
             continue
         }
-        
-        switch filter.Op {
-        case EqualTo:
-            filterExpression = filterExpression.And(field.Equal(expression.Value(filter.Value)))
-        case LessThan:
-            filterExpression = filterExpression.And(field.LessThan(expression.Value(filter.Value)))
-        case GreaterThan:
-            filterExpression = filterExpression.And(field.GreaterThan(expression.Value(filter.Value)))
-        case MatchAny:
-            filterExpression = filterExpression.And(expression.Contains(field, filter.Value.(string)))
-        case MatchExact:
-            filterExpression = filterExpression.And(field.Equal(expression.Value(filter.Value)))
-        case MatchSuperset:
-            filterExpression = filterExpression.And(expression.Contains(field, filter.Value.(string)))
-        case MatchSubset:
-            filterExpression = filterExpression.And(expression.Contains(field, filter.Value.(string)))
+        if filter.Combinator == 0 && sortKey != "" && filter.Name == sortKey && !hasSortCondition {
+            condition, ok, err := sortKeyCondition(filter)
+            if err != nil {
+                return nil, fmt.Errorf("invalid sort key condition: %w", err)
+            }
+            if ok {
+                sortCondition, hasSortCondition = condition, true
+                continue
+            }
         }
+        remaining = append(remaining, filter)
     }
 
-    builder := expression.NewBuilder().WithKeyCondition(keyCondition)
-    if len(projection) > 0 {
-        projBuilder := expression.NamesList(expression.Name(projection[0]))
-        for _, attr := range projection[1:] {
-            projBuilder = projBuilder.AddNames(expression.Name(attr))
-        }
-        builder = builder.WithProjection(projBuilder)
+    if hasSortCondition {
+        keyCondition = expression.KeyAnd(keyCondition, sortCondition)
     }
 
-    if hasFilters {
-        builder = builder.WithFilter(filterExpression)
+    filterExpression, hasFilters, verify, err := buildFilterExpression(remaining)
+    if err != nil {
+        return nil, fmt.Errorf("invalid filters: %w", err)
+    }
+
+    var verificationOnly []string
+    if verify {
+        projection, verificationOnly = verificationProjection(projection, remaining)
+    }
+
+    if keepKeys {
+        var keyOnly []string
+        projection, keyOnly = extendProjection(projection, append([]string{partitionKey, sortKey}, settings.tableKeys...))
+        verificationOnly = append(verificationOnly, keyOnly...)
+    }
+
+    build := func(filterExpression expression.ConditionBuilder, hasFilters bool) (expression.Expression, error) {
+        builder := expression.NewBuilder().WithKeyCondition(keyCondition)
+        if len(projection) > 0 {
+            projBuilder := expression.NamesList(expression.Name(projection[0]))
+            for _, attr := range projection[1:] {
+                projBuilder = projBuilder.AddNames(expression.Name(attr))
+            }
+            builder = builder.WithProjection(projBuilder)
+        }
+
+        if hasFilters {
+            builder = builder.WithFilter(filterExpression)
+        }
+
+        expr, err := builder.Build()
+        if err != nil {
+            return expr, fmt.Errorf("error to building expression: %w", err)
+        }
+        return expr, nil
     }
 
-    expr, err := builder.Build()
+    expr, err := build(filterExpression, hasFilters)
     if err != nil {
-        return nil, nil, fmt.Errorf("error to building expression: %w", err)
+        return nil, err
+    }
+    fingerprint := queryFingerprint(kind, expr, settings)
+
+    // The expiry filter changes with the clock, so it is left out of the
+    // fingerprint and tokens stay valid from one page to the next.
+    if settings.excludeExpired {
+        if settings.expiry == "" {
+            return nil, errors.New("ExcludeExpired needs the item type, use ListItems, ListItemsIter or CountItemsOf")
+        }
+        unexpired := unexpiredCondition(settings.expiry, time.Now())
+        if hasFilters {
+            unexpired = expression.And(filterExpression, unexpired)
+        }
+        filterExpression, hasFilters = unexpired, true
+        if expr, err = build(filterExpression, hasFilters); err != nil {
+            return nil, err
+        }
     }
 
     input := &dynamodb.QueryInput{
@@ -109,9 +190,65 @@ func ListItems[T any](
         input.FilterExpression = expr.Filter()
     }
 
+    if settings.direction == Descending {
+        input.ScanIndexForward = aws.Bool(false)
+    }
+    if settings.index != "" {
+        input.IndexName = aws.String(settings.index)
+    }
+
+    return &queryRequest{
+        input:            input,
+        fingerprint:      fingerprint,
+        remaining:        remaining,
+        verify:           verify,
+        verificationOnly: verificationOnly,
+    }, nil
+}
+
+// verifyPage drops the items of a page that fail local verification.
+func (r *queryRequest) verifyPage(items []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+    if !r.verify {
+        return items, nil
+    }
+    items, err := verifyItems(r.remaining, items)
+    if err != nil {
+        return nil, fmt.Errorf("failed to verify records: %w", err)
+    }
+    return items, nil
+}
+
+func ListItems[T any](
+    ctx context.Context,
+    kind string,
+    dynamoClient dynamoQueryClient, 
+    partitionKey string,
+    sortKey string,
+    filters []Filter,
+    pagination *Pagination,
+    projection []string,
+    opts ...Option,
+) ([]T, *Pagination, error) {
+
+    fill := pagination != nil && pagination.Fill && pagination.Limit > 0
+    settings, err := withExpiry[T](newOptions(opts))
+    if err != nil {
+        return nil, nil, err
+    }
+    times, err := timeCodecFor[T]()
+    if err != nil {
+        return nil, nil, err
+    }
+    filters = times.encodeFilters(filters)
+    request, err := newQueryRequest(kind, partitionKey, sortKey, filters, projection, fill, settings)
+    if err != nil {
+        return nil, nil, err
+    }
+    input := request.input
+
     if pagination != nil && pagination.Token != "" {
-        input.ExclusiveStartKey = map[string]types.AttributeValue{
-            partitionKey: &types.AttributeValueMemberS{Value: pagination.Token},
+        if input.ExclusiveStartKey, err = decodeToken(kind, request.fingerprint, pagination.Token, pagination.Signer); err != nil {
+            return nil, nil, err
         }
     }
 
@@ -120,7 +257,12 @@ func ListItems[T any](
         input.Limit = &limit
     }
 
+    if pagination != nil {
+        pagination.NextToken = ""
+    }
+
     var results []T
+    var keyNames map[string]types.AttributeValue
     queryPaginator := dynamodb.NewQueryPaginator(dynamoClient, input)
     for queryPaginator.HasMorePages() {
         page, err := queryPaginator.NextPage(ctx)
@@ -128,15 +270,36 @@ func ListItems[T any](
             return nil, nil, fmt.Errorf("fail to : %w", err)
         }
 
-        var pageResults []T
-        if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageResults); err != nil {
+        items, err := request.verifyPage(page.Items)
+        if err != nil {
+            return nil, nil, err
+        }
+
+        lastKey := page.LastEvaluatedKey
+        if fill {
+            if lastKey != nil {
+                keyNames = lastKey
+            }
+            if need := pagination.Limit - len(results); len(items) > need {
+                items = items[:need]
+                if lastKey, err = itemKey(items[need-1], keyNames); err != nil {
+                    return nil, nil, fmt.Errorf("failed to encode pagination token: %w", err)
+                }
+            }
+        }
+        stripAttributes(items, request.verificationOnly)
+
+        pageResults, err := unmarshalRecords[T](times, items)
+        if err != nil {
             return nil, nil, fmt.Errorf("failed to fetch records: %w", err)
         }
 
         results = append(results, pageResults...)
 
-        if pagination != nil && page.LastEvaluatedKey != nil {
-            pagination.NextToken = page.LastEvaluatedKey[partitionKey].(*types.AttributeValueMemberS).Value
+        if pagination != nil && lastKey != nil && (!fill || len(results) == pagination.Limit) {
+            if pagination.NextToken, err = encodeToken(kind, request.fingerprint, lastKey, pagination.Signer); err != nil {
+                return nil, nil, fmt.Errorf("failed to encode pagination token: %w", err)
+            }
             break
         }
     }
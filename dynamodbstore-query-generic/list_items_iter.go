@@ -0,0 +1,73 @@
+package dynamodbstore
+
+import (
+    "context"
+    "fmt"
+    "iter"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// ListItemsIter is the streaming form of ListItems: it fetches one Query page
+// at a time as the sequence is ranged over, so memory does not grow with the
+// result set. Breaking out of the loop stops the reads; the sequence ends
+// after yielding an error.
+func ListItemsIter[T any](
+    ctx context.Context,
+    kind string,
+    dynamoClient dynamoQueryClient,
+    partitionKey string,
+    sortKey string,
+    filters []Filter,
+    projection []string,
+    opts ...Option,
+) iter.Seq2[T, error] {
+    return func(yield func(T, error) bool) {
+        var zero T
+
+        settings, err := withExpiry[T](newOptions(opts))
+        if err != nil {
+            yield(zero, err)
+            return
+        }
+        times, err := timeCodecFor[T]()
+        if err != nil {
+            yield(zero, err)
+            return
+        }
+        filters := times.encodeFilters(filters)
+        request, err := newQueryRequest(kind, partitionKey, sortKey, filters, projection, false, settings)
+        if err != nil {
+            yield(zero, err)
+            return
+        }
+
+        queryPaginator := dynamodb.NewQueryPaginator(dynamoClient, request.input)
+        for queryPaginator.HasMorePages() {
+            page, err := queryPaginator.NextPage(ctx)
+            if err != nil {
+                yield(zero, fmt.Errorf("failed to fetch records: %w", err))
+                return
+            }
+
+            items, err := request.verifyPage(page.Items)
+            if err != nil {
+                yield(zero, err)
+                return
+            }
+            stripAttributes(items, request.verificationOnly)
+
+            pageResults, err := unmarshalRecords[T](times, items)
+            if err != nil {
+                yield(zero, fmt.Errorf("failed to fetch records: %w", err))
+                return
+            }
+
+            for _, result := range pageResults {
+                if !yield(result, nil) {
+                    return
+                }
+            }
+        }
+    }
+}
@@ -0,0 +1,73 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func bundleItem(id string) map[string]types.AttributeValue {
+    return map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}}
+}
+
+func TestListItemsIterReadsEveryPage(t *testing.T) {
+    ctx := context.Background()
+    filters := []Filter{{Name: "ID", Op: EqualTo, Value: "bundle"}}
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{
+        Items:            []map[string]types.AttributeValue{bundleItem("a"), bundleItem("b")},
+        LastEvaluatedKey: bundleItem("b"),
+    }, nil).Once()
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{
+        Items: []map[string]types.AttributeValue{bundleItem("c")},
+    }, nil).Once()
+
+    var ids []string
+    for bundle, err := range ListItemsIter[Bundle](ctx, "BundlesTable", mockClient, "ID", "", filters, nil) {
+        assert.NoError(t, err)
+        ids = append(ids, bundle.ID)
+    }
+    assert.Equal(t, []string{"a", "b", "c"}, ids)
+    mockClient.AssertNumberOfCalls(t, "Query", 2)
+}
+
+func TestListItemsIterStopsOnBreak(t *testing.T) {
+    ctx := context.Background()
+    filters := []Filter{{Name: "ID", Op: EqualTo, Value: "bundle"}}
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{
+        Items:            []map[string]types.AttributeValue{bundleItem("a"), bundleItem("b")},
+        LastEvaluatedKey: bundleItem("b"),
+    }, nil)
+
+    for bundle, err := range ListItemsIter[Bundle](ctx, "BundlesTable", mockClient, "ID", "", filters, nil) {
+        assert.NoError(t, err)
+        assert.Equal(t, "a", bundle.ID)
+        break
+    }
+    mockClient.AssertNumberOfCalls(t, "Query", 1)
+}
+
+func TestListItemsIterYieldsErrors(t *testing.T) {
+    ctx := context.Background()
+    filters := []Filter{{Name: "ID", Op: EqualTo, Value: "bundle"}}
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return((*dynamodb.QueryOutput)(nil), errors.New("throttled"))
+
+    var errs []error
+    for _, err := range ListItemsIter[Bundle](ctx, "BundlesTable", mockClient, "ID", "", filters, nil) {
+        errs = append(errs, err)
+    }
+    if assert.Len(t, errs, 1) {
+        assert.ErrorContains(t, errs[0], "throttled")
+    }
+
+    for _, err := range ListItemsIter[Bundle](ctx, "BundlesTable", mockClient, "ID", "", []Filter{Or()}, nil) {
+        assert.Error(t, err)
+    }
+}
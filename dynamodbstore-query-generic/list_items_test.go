@@ -57,6 +57,66 @@ func (m *MockDynamoDBClient) Query(ctx context.Context, input *dynamodb.QueryInp
     return args.Get(0).(*dynamodb.QueryOutput), args.Error(1)
 }
 
+func (m *MockDynamoDBClient) GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.GetItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.PutItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.DeleteItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.UpdateItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.BatchGetItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.BatchWriteItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.TransactWriteItemsOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) TransactGetItems(ctx context.Context, input *dynamodb.TransactGetItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.TransactGetItemsOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) CreateTable(ctx context.Context, input *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.CreateTableOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.DescribeTableOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) UpdateTimeToLive(ctx context.Context, input *dynamodb.UpdateTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.UpdateTimeToLiveOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) DescribeTimeToLive(ctx context.Context, input *dynamodb.DescribeTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error) {
+    args := m.Called(ctx, input)
+    return args.Get(0).(*dynamodb.DescribeTimeToLiveOutput), args.Error(1)
+}
+
 // Configures and runs a generic test with a specific type
 func setupGenericTest[T any](mockClient *MockDynamoDBClient, items []map[string]types.AttributeValue, t *testing.T, projection []string, tableName, partitionKey string) []T {
     ctx := context.Background()
@@ -69,7 +129,7 @@ func setupGenericTest[T any](mockClient *MockDynamoDBClient, items []map[string]
     filters := []Filter{{Name: partitionKey, Op: EqualTo, Value: "test-partition-key"}}
     pagination := &Pagination{Limit: 10}
 
-    results, _, err := ListItems[T](ctx, tableName, mockClient, partitionKey, filters, pagination, projection)
+    results, _, err := ListItems[T](ctx, tableName, mockClient, partitionKey, "", filters, pagination, projection)
     assert.NoError(t, err)
     assert.NotNil(t, results)
 
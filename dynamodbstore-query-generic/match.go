@@ -0,0 +1,449 @@
+package dynamodbstore
+
+import (
+    "bytes"
+    "fmt"
+    "math/big"
+    "strconv"
+    "strings"
+    "unicode/utf8"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// matchFilters reports whether item satisfies every filter, evaluating them
+// the way DynamoDB would. It backs the parts of a filter the server cannot
+// express.
+func matchFilters(filters []Filter, item map[string]types.AttributeValue) (bool, error) {
+    return matchFilter(And(filters...), item)
+}
+
+func matchFilter(filter Filter, item map[string]types.AttributeValue) (bool, error) {
+    switch filter.Combinator {
+    case 0:
+        return matchLeaf(filter, item)
+    case CombineNot:
+        matched, err := matchFilter(And(filter.Filters...), item)
+        return !matched, err
+    case CombineAnd, CombineOr:
+        for _, child := range filter.Filters {
+            matched, err := matchFilter(child, item)
+            if err != nil {
+                return false, err
+            }
+            if matched == (filter.Combinator == CombineOr) {
+                return matched, nil
+            }
+        }
+        return filter.Combinator == CombineAnd, nil
+    }
+
+    return false, fmt.Errorf("unknown combinator: %d", filter.Combinator)
+}
+
+func matchLeaf(filter Filter, item map[string]types.AttributeValue) (bool, error) {
+    attribute, found := attributeAt(item, filter.Name)
+
+    switch filter.Op {
+    case MatchAny, MatchSuperset, MatchSubset, In:
+        values, err := setValues(filter)
+        if err != nil {
+            return false, err
+        }
+        members, err := marshalValues(filter.Name, values)
+        if err != nil || !found {
+            return false, err
+        }
+        if filter.Op == In {
+            return containsElement(members, attribute), nil
+        }
+        return matchSet(filter.Op, attribute, members), nil
+    case BeginsWith:
+        prefix, err := prefixValue(filter)
+        if err != nil || !found {
+            return false, err
+        }
+        s, ok := attribute.(*types.AttributeValueMemberS)
+        return ok && strings.HasPrefix(s.Value, prefix), nil
+    case AttributeExists:
+        return found, nil
+    case AttributeNotExists:
+        return !found, nil
+    case AttributeType:
+        attributeType, err := typeValue(filter)
+        if err != nil || !found {
+            return false, err
+        }
+        return attributeTypeOf(attribute) == attributeType, nil
+    case Size:
+        comparison, err := sizeValue(filter)
+        if err != nil || !found {
+            return false, err
+        }
+        size, ok := attributeSize(attribute)
+        if !ok {
+            return false, nil
+        }
+        return compareTo(filter.Name, comparison.Op, &types.AttributeValueMemberN{Value: strconv.Itoa(size)}, comparison.Value)
+    case NotEqual:
+        if !found {
+            return true, nil
+        }
+    }
+
+    if !found {
+        return false, nil
+    }
+    return compareTo(filter.Name, filter.Op, attribute, filter.Value)
+}
+
+// compareTo evaluates a scalar comparison of attribute against value.
+func compareTo(name string, op MatchBehavior, attribute types.AttributeValue, value interface{}) (bool, error) {
+    if op == Between {
+        bounds, err := rangeValue(name, value)
+        if err != nil {
+            return false, err
+        }
+        limits, err := marshalValues(name, []interface{}{bounds.Lower, bounds.Upper})
+        if err != nil {
+            return false, err
+        }
+        fromLower, okLower := compareValues(attribute, limits[0])
+        toUpper, okUpper := compareValues(attribute, limits[1])
+        return okLower && okUpper && fromLower >= 0 && toUpper <= 0, nil
+    }
+
+    marshalled, err := marshalValues(name, []interface{}{value})
+    if err != nil {
+        return false, err
+    }
+
+    switch op {
+    case EqualTo, MatchExact:
+        return equalValues(attribute, marshalled[0]), nil
+    case NotEqual:
+        return !equalValues(attribute, marshalled[0]), nil
+    case LessThan, LessOrEqual, GreaterThan, GreaterOrEqual:
+        c, ok := compareValues(attribute, marshalled[0])
+        if !ok {
+            return false, nil
+        }
+        switch op {
+        case LessThan:
+            return c < 0, nil
+        case LessOrEqual:
+            return c <= 0, nil
+        case GreaterThan:
+            return c > 0, nil
+        }
+        return c >= 0, nil
+    }
+
+    return false, fmt.Errorf("unknown operation for %q: %d", name, op)
+}
+
+func marshalValues(name string, values []interface{}) ([]types.AttributeValue, error) {
+    marshalled := make([]types.AttributeValue, len(values))
+    for i, value := range values {
+        av, err := attributevalue.Marshal(value)
+        if err != nil {
+            return nil, fmt.Errorf("failed to marshal filter value for %q: %w", name, err)
+        }
+        marshalled[i] = av
+    }
+    return marshalled, nil
+}
+
+func matchSet(op MatchBehavior, attribute types.AttributeValue, values []types.AttributeValue) bool {
+    switch op {
+    case MatchAny:
+        for _, value := range values {
+            if containsValue(attribute, value) {
+                return true
+            }
+        }
+        return false
+    case MatchSuperset:
+        for _, value := range values {
+            if !containsValue(attribute, value) {
+                return false
+            }
+        }
+        return true
+    }
+
+    elements := setElements(attribute)
+    if len(elements) == 0 {
+        return false
+    }
+    for _, element := range elements {
+        if !containsElement(values, element) {
+            return false
+        }
+    }
+    return true
+}
+
+// containsValue mirrors DynamoDB's contains(): substring on strings and
+// binaries, membership on sets and lists.
+func containsValue(attribute, value types.AttributeValue) bool {
+    switch a := attribute.(type) {
+    case *types.AttributeValueMemberS:
+        v, ok := value.(*types.AttributeValueMemberS)
+        return ok && strings.Contains(a.Value, v.Value)
+    case *types.AttributeValueMemberB:
+        v, ok := value.(*types.AttributeValueMemberB)
+        return ok && bytes.Contains(a.Value, v.Value)
+    case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS, *types.AttributeValueMemberL:
+        return containsElement(setElements(attribute), value)
+    }
+    return false
+}
+
+func containsElement(elements []types.AttributeValue, value types.AttributeValue) bool {
+    for _, element := range elements {
+        if equalValues(element, value) {
+            return true
+        }
+    }
+    return false
+}
+
+// setElements returns the members of a set or list attribute; any other
+// attribute is its own single element.
+func setElements(attribute types.AttributeValue) []types.AttributeValue {
+    switch a := attribute.(type) {
+    case *types.AttributeValueMemberSS:
+        elements := make([]types.AttributeValue, len(a.Value))
+        for i, v := range a.Value {
+            elements[i] = &types.AttributeValueMemberS{Value: v}
+        }
+        return elements
+    case *types.AttributeValueMemberNS:
+        elements := make([]types.AttributeValue, len(a.Value))
+        for i, v := range a.Value {
+            elements[i] = &types.AttributeValueMemberN{Value: v}
+        }
+        return elements
+    case *types.AttributeValueMemberBS:
+        elements := make([]types.AttributeValue, len(a.Value))
+        for i, v := range a.Value {
+            elements[i] = &types.AttributeValueMemberB{Value: v}
+        }
+        return elements
+    case *types.AttributeValueMemberL:
+        return a.Value
+    }
+    return []types.AttributeValue{attribute}
+}
+
+func equalValues(a, b types.AttributeValue) bool {
+    switch x := a.(type) {
+    case *types.AttributeValueMemberS:
+        y, ok := b.(*types.AttributeValueMemberS)
+        return ok && x.Value == y.Value
+    case *types.AttributeValueMemberN:
+        c, ok := compareValues(a, b)
+        return ok && c == 0
+    case *types.AttributeValueMemberB:
+        y, ok := b.(*types.AttributeValueMemberB)
+        return ok && bytes.Equal(x.Value, y.Value)
+    case *types.AttributeValueMemberBOOL:
+        y, ok := b.(*types.AttributeValueMemberBOOL)
+        return ok && x.Value == y.Value
+    case *types.AttributeValueMemberNULL:
+        _, ok := b.(*types.AttributeValueMemberNULL)
+        return ok
+    case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+        if fmt.Sprintf("%T", a) != fmt.Sprintf("%T", b) {
+            return false
+        }
+        left, right := setElements(a), setElements(b)
+        if len(left) != len(right) {
+            return false
+        }
+        for _, element := range left {
+            if !containsElement(right, element) {
+                return false
+            }
+        }
+        return true
+    case *types.AttributeValueMemberL:
+        y, ok := b.(*types.AttributeValueMemberL)
+        if !ok || len(x.Value) != len(y.Value) {
+            return false
+        }
+        for i := range x.Value {
+            if !equalValues(x.Value[i], y.Value[i]) {
+                return false
+            }
+        }
+        return true
+    case *types.AttributeValueMemberM:
+        y, ok := b.(*types.AttributeValueMemberM)
+        if !ok || len(x.Value) != len(y.Value) {
+            return false
+        }
+        for k, v := range x.Value {
+            if other, found := y.Value[k]; !found || !equalValues(v, other) {
+                return false
+            }
+        }
+        return true
+    }
+    return false
+}
+
+// compareValues orders two scalars of the same type. ok is false when the
+// values are not comparable, in which case DynamoDB treats the comparison as
+// false.
+func compareValues(a, b types.AttributeValue) (c int, ok bool) {
+    switch x := a.(type) {
+    case *types.AttributeValueMemberS:
+        if y, ok := b.(*types.AttributeValueMemberS); ok {
+            return strings.Compare(x.Value, y.Value), true
+        }
+    case *types.AttributeValueMemberB:
+        if y, ok := b.(*types.AttributeValueMemberB); ok {
+            return bytes.Compare(x.Value, y.Value), true
+        }
+    case *types.AttributeValueMemberN:
+        y, ok := b.(*types.AttributeValueMemberN)
+        if !ok {
+            return 0, false
+        }
+        left, okLeft := new(big.Rat).SetString(x.Value)
+        right, okRight := new(big.Rat).SetString(y.Value)
+        if !okLeft || !okRight {
+            return 0, false
+        }
+        return left.Cmp(right), true
+    }
+    return 0, false
+}
+
+func attributeTypeOf(attribute types.AttributeValue) expression.DynamoDBAttributeType {
+    switch attribute.(type) {
+    case *types.AttributeValueMemberS:
+        return expression.String
+    case *types.AttributeValueMemberSS:
+        return expression.StringSet
+    case *types.AttributeValueMemberN:
+        return expression.Number
+    case *types.AttributeValueMemberNS:
+        return expression.NumberSet
+    case *types.AttributeValueMemberB:
+        return expression.Binary
+    case *types.AttributeValueMemberBS:
+        return expression.BinarySet
+    case *types.AttributeValueMemberBOOL:
+        return expression.Boolean
+    case *types.AttributeValueMemberNULL:
+        return expression.Null
+    case *types.AttributeValueMemberL:
+        return expression.List
+    case *types.AttributeValueMemberM:
+        return expression.Map
+    }
+    return ""
+}
+
+// attributeSize mirrors DynamoDB's size(): characters of a string, bytes of
+// a binary and elements of a set, list or map.
+func attributeSize(attribute types.AttributeValue) (int, bool) {
+    switch a := attribute.(type) {
+    case *types.AttributeValueMemberS:
+        return utf8.RuneCountInString(a.Value), true
+    case *types.AttributeValueMemberB:
+        return len(a.Value), true
+    case *types.AttributeValueMemberL:
+        return len(a.Value), true
+    case *types.AttributeValueMemberM:
+        return len(a.Value), true
+    case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+        return len(setElements(attribute)), true
+    }
+    return 0, false
+}
+
+// attributeAt resolves a dotted document path inside item.
+func attributeAt(item map[string]types.AttributeValue, name string) (types.AttributeValue, bool) {
+    path := strings.Split(name, ".")
+    attribute, found := item[path[0]]
+    for _, part := range path[1:] {
+        m, ok := attribute.(*types.AttributeValueMemberM)
+        if !found || !ok {
+            return nil, false
+        }
+        attribute, found = m.Value[part]
+    }
+    return attribute, found
+}
+
+// verificationProjection extends projection with the top-level attributes
+// filters read, so they can be verified locally. It returns the names it had
+// to add so they can be dropped before decoding.
+func verificationProjection(projection []string, filters []Filter) ([]string, []string) {
+    return extendProjection(projection, filterNames(And(filters...)))
+}
+
+// extendProjection appends the top-level names projection lacks and returns
+// the ones it added. An empty projection already reads every attribute.
+func extendProjection(projection []string, names []string) ([]string, []string) {
+    if len(projection) == 0 {
+        return projection, nil
+    }
+
+    present := make(map[string]bool, len(projection))
+    for _, attr := range projection {
+        present[strings.Split(attr, ".")[0]] = true
+    }
+
+    var added []string
+    for _, name := range names {
+        name = strings.Split(name, ".")[0]
+        if name != "" && !present[name] {
+            present[name] = true
+            added = append(added, name)
+        }
+    }
+    return append(append([]string{}, projection...), added...), added
+}
+
+func filterNames(filter Filter) []string {
+    if filter.Combinator == 0 {
+        return []string{filter.Name}
+    }
+    var names []string
+    for _, child := range filter.Filters {
+        names = append(names, filterNames(child)...)
+    }
+    return names
+}
+
+// verifyItems keeps the items matching filters.
+func verifyItems(filters []Filter, items []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+    var matched []map[string]types.AttributeValue
+    for _, item := range items {
+        ok, err := matchFilters(filters, item)
+        if err != nil {
+            return nil, err
+        }
+        if ok {
+            matched = append(matched, item)
+        }
+    }
+    return matched, nil
+}
+
+// stripAttributes drops the attributes that were only projected for internal
+// use.
+func stripAttributes(items []map[string]types.AttributeValue, names []string) {
+    for _, item := range items {
+        for _, name := range names {
+            delete(item, name)
+        }
+    }
+}
@@ -0,0 +1,53 @@
+package dynamodbstore
+
+// Option adjusts how ListItems reads the table.
+type Option func(*options)
+
+type options struct {
+    direction      SortDirection
+    index          string
+    excludeExpired bool
+    expiry         string   // epoch attribute, resolved from the item type
+    tableKeys      []string // kept with the index keys when a page is cut
+}
+
+func newOptions(opts []Option) options {
+    var o options
+    for _, opt := range opts {
+        opt(&o)
+    }
+    return o
+}
+
+// SortDirection is the order in which a Query walks the sort key.
+type SortDirection int
+
+const (
+    Ascending SortDirection = iota
+    Descending
+)
+
+// WithSortDirection sets ScanIndexForward. Tokens carry the direction, so a
+// descending listing keeps paging towards older items and its tokens are not
+// accepted by an ascending one.
+func WithSortDirection(direction SortDirection) Option {
+    return func(o *options) {
+        o.direction = direction
+    }
+}
+
+// WithIndex queries the named secondary index instead of the table; the
+// partition and sort keys given to ListItems are then the index keys.
+func WithIndex(name string) Option {
+    return func(o *options) {
+        o.index = name
+    }
+}
+
+// withTableKeys names the table keys of an index query. A page cut at an
+// item resumes from its index and table keys, so both must be projected.
+func withTableKeys(names ...string) Option {
+    return func(o *options) {
+        o.tableKeys = names
+    }
+}
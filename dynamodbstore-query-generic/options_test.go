@@ -0,0 +1,48 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestListItemsSortDirection(t *testing.T) {
+    ctx := context.Background()
+    lastKey := map[string]types.AttributeValue{
+        "NodeID":    &types.AttributeValueMemberS{Value: "node1"},
+        "Timestamp": &types.AttributeValueMemberS{Value: "2024-01-02T00:00:00Z"},
+    }
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{LastEvaluatedKey: lastKey}, nil)
+
+    filters := []Filter{{Name: "NodeID", Op: EqualTo, Value: "node1"}}
+    _, _, err := ListItems[NodeEvent](ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, &Pagination{}, nil)
+    assert.NoError(t, err)
+    assert.Nil(t, mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput).ScanIndexForward)
+
+    pagination := &Pagination{Limit: 10}
+    _, pagination, err = ListItems[NodeEvent](ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, pagination, nil, WithSortDirection(Descending))
+    assert.NoError(t, err)
+    input := mockClient.Calls[1].Arguments.Get(1).(*dynamodb.QueryInput)
+    if assert.NotNil(t, input.ScanIndexForward) {
+        assert.False(t, *input.ScanIndexForward)
+    }
+
+    // The next page keeps going backwards from the same key
+    next := &Pagination{Token: pagination.NextToken, Limit: 10}
+    _, _, err = ListItems[NodeEvent](ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, next, nil, WithSortDirection(Descending))
+    assert.NoError(t, err)
+    assert.Equal(t, lastKey, mockClient.Calls[2].Arguments.Get(1).(*dynamodb.QueryInput).ExclusiveStartKey)
+
+    // but cannot be replayed in ascending order
+    var tokenErr *TokenError
+    _, _, err = ListItems[NodeEvent](ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, &Pagination{Token: pagination.NextToken}, nil)
+    if assert.True(t, errors.As(err, &tokenErr), "%v", err) {
+        assert.Equal(t, TokenWrongQuery, tokenErr.Reason)
+    }
+}
@@ -0,0 +1,374 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "reflect"
+    "sort"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// dynamoTableClient is the part of the DynamoDB API Provision needs.
+type dynamoTableClient interface {
+    CreateTable(ctx context.Context, input *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+    DescribeTable(ctx context.Context, input *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+    UpdateTimeToLive(ctx context.Context, input *dynamodb.UpdateTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error)
+    DescribeTimeToLive(ctx context.Context, input *dynamodb.DescribeTimeToLiveInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error)
+}
+
+const defaultPollInterval = 2 * time.Second
+
+// Provisioner creates the tables of the schemas in a Registry.
+type Provisioner struct {
+    Client       dynamoTableClient
+    PollInterval time.Duration // 2s unless set
+}
+
+// Provision creates every table of registry that does not exist yet, waits
+// until each table and its global indexes are ACTIVE and enables Time to
+// Live where a schema asks for it. Tables that already exist are left as
+// they are but must have the keys and indexes of their schemas, so running
+// it again is harmless.
+func (p *Provisioner) Provision(ctx context.Context, registry *Registry) error {
+    definitions, err := registry.tableDefinitions()
+    if err != nil {
+        return err
+    }
+    for _, definition := range definitions {
+        if err := p.provision(ctx, definition); err != nil {
+            return fmt.Errorf("failed to provision table %q: %w", definition.name, err)
+        }
+    }
+    return nil
+}
+
+func (p *Provisioner) provision(ctx context.Context, definition *tableDefinition) error {
+    _, err := p.Client.CreateTable(ctx, definition.createTableInput())
+    var inUse *types.ResourceInUseException
+    if err != nil && !errors.As(err, &inUse) {
+        return err
+    }
+
+    table, err := p.waitActive(ctx, definition.name)
+    if err != nil {
+        return err
+    }
+    if err := definition.check(table); err != nil {
+        return err
+    }
+    if definition.ttl == "" {
+        return nil
+    }
+    return p.enableTTL(ctx, definition.name, definition.ttl)
+}
+
+// waitActive polls the table until it and its global indexes are ACTIVE.
+func (p *Provisioner) waitActive(ctx context.Context, name string) (*types.TableDescription, error) {
+    interval := p.PollInterval
+    if interval <= 0 {
+        interval = defaultPollInterval
+    }
+
+    for {
+        output, err := p.Client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+        if err != nil {
+            return nil, err
+        }
+        if active(output.Table) {
+            return output.Table, nil
+        }
+
+        select {
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        case <-time.After(interval):
+        }
+    }
+}
+
+func active(table *types.TableDescription) bool {
+    if table == nil || table.TableStatus != types.TableStatusActive {
+        return false
+    }
+    for _, index := range table.GlobalSecondaryIndexes {
+        if index.IndexStatus != types.IndexStatusActive {
+            return false
+        }
+    }
+    return true
+}
+
+func (p *Provisioner) enableTTL(ctx context.Context, name, attribute string) error {
+    output, err := p.Client.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String(name)})
+    if err != nil {
+        return err
+    }
+    if current := output.TimeToLiveDescription; current != nil {
+        switch current.TimeToLiveStatus {
+        case types.TimeToLiveStatusEnabled, types.TimeToLiveStatusEnabling:
+            if aws.ToString(current.AttributeName) == attribute {
+                return nil
+            }
+            return fmt.Errorf("time to live is already enabled on %q", aws.ToString(current.AttributeName))
+        }
+    }
+
+    _, err = p.Client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+        TableName: aws.String(name),
+        TimeToLiveSpecification: &types.TimeToLiveSpecification{
+            AttributeName: aws.String(attribute),
+            Enabled:       aws.Bool(true),
+        },
+    })
+    return err
+}
+
+// tableDefinition merges what the schemas sharing a table ask of it.
+type tableDefinition struct {
+    name         string
+    partitionKey string
+    sortKey      string
+    attributes   map[string]types.ScalarAttributeType
+    global       map[string]types.GlobalSecondaryIndex
+    local        map[string]types.LocalSecondaryIndex
+    throughput   *types.ProvisionedThroughput
+    stream       types.StreamViewType
+    ttl          string
+}
+
+// tableDefinitions returns one definition per table, ordered by name.
+func (r *Registry) tableDefinitions() ([]*tableDefinition, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    schemas := make([]*schema, 0, len(r.schemas))
+    for _, s := range r.schemas {
+        schemas = append(schemas, s)
+    }
+    sort.Slice(schemas, func(i, j int) bool {
+        if schemas[i].Table != schemas[j].Table {
+            return schemas[i].Table < schemas[j].Table
+        }
+        return schemas[i].itemType.String() < schemas[j].itemType.String()
+    })
+
+    var definitions []*tableDefinition
+    for _, s := range schemas {
+        definition, err := s.tableDefinition()
+        if err != nil {
+            return nil, fmt.Errorf("invalid schema for %v: %w", s.itemType, err)
+        }
+        if n := len(definitions); n > 0 && definitions[n-1].name == definition.name {
+            if err := definitions[n-1].merge(definition); err != nil {
+                return nil, fmt.Errorf("schema for %v conflicts with table %q: %w", s.itemType, definition.name, err)
+            }
+            continue
+        }
+        definitions = append(definitions, definition)
+    }
+    return definitions, nil
+}
+
+func (s *schema) tableDefinition() (*tableDefinition, error) {
+    definition := &tableDefinition{
+        name:         s.Table,
+        partitionKey: s.PartitionKey,
+        sortKey:      s.SortKey,
+        attributes:   make(map[string]types.ScalarAttributeType),
+        global:       make(map[string]types.GlobalSecondaryIndex),
+        local:        make(map[string]types.LocalSecondaryIndex),
+        throughput:   s.Throughput,
+        stream:       s.Stream,
+        ttl:          s.TTLAttribute,
+    }
+
+    if table := s.SingleTable; table != nil {
+        definition.attributes[table.PartitionKey] = types.ScalarAttributeTypeS
+        definition.attributes[table.SortKey] = types.ScalarAttributeTypeS
+        if table.TypeIndex != "" {
+            definition.attributes[table.TypeAttribute] = types.ScalarAttributeTypeS
+            definition.global[table.TypeIndex] = types.GlobalSecondaryIndex{
+                IndexName:  aws.String(table.TypeIndex),
+                KeySchema:  keySchema(table.TypeAttribute, table.SortKey),
+                Projection: indexProjection(nil),
+            }
+        }
+        return definition, nil
+    }
+
+    for _, name := range []string{s.PartitionKey, s.SortKey} {
+        if err := definition.addAttribute(s, name); err != nil {
+            return nil, err
+        }
+    }
+    for _, index := range s.Indexes {
+        partitionKey, sortKey, err := s.keys(index.Name)
+        if err != nil {
+            return nil, err
+        }
+        for _, name := range []string{partitionKey, sortKey} {
+            if err := definition.addAttribute(s, name); err != nil {
+                return nil, err
+            }
+        }
+
+        if index.Local {
+            definition.local[index.Name] = types.LocalSecondaryIndex{
+                IndexName:  aws.String(index.Name),
+                KeySchema:  keySchema(partitionKey, sortKey),
+                Projection: indexProjection(index.Projection),
+            }
+            continue
+        }
+        definition.global[index.Name] = types.GlobalSecondaryIndex{
+            IndexName:  aws.String(index.Name),
+            KeySchema:  keySchema(partitionKey, sortKey),
+            Projection: indexProjection(index.Projection),
+        }
+    }
+    return definition, nil
+}
+
+func (d *tableDefinition) addAttribute(s *schema, name string) error {
+    if name == "" {
+        return nil
+    }
+    attributeType, err := s.keyType(name)
+    if err != nil {
+        return err
+    }
+    d.attributes[name] = attributeType
+    return nil
+}
+
+// merge adds the indexes of other, a definition of the same table. Both
+// must have the same keys; throughput, stream and time to live left unset
+// by one are taken from the other, and only differing settings conflict.
+func (d *tableDefinition) merge(other *tableDefinition) error {
+    if d.partitionKey != other.partitionKey || d.sortKey != other.sortKey {
+        return errors.New("different keys")
+    }
+    switch {
+    case d.throughput != nil && other.throughput != nil && !reflect.DeepEqual(d.throughput, other.throughput):
+        return errors.New("different throughput")
+    case d.stream != "" && other.stream != "" && d.stream != other.stream:
+        return errors.New("different stream")
+    case d.ttl != "" && other.ttl != "" && d.ttl != other.ttl:
+        return errors.New("different time to live attribute")
+    }
+    if d.throughput == nil {
+        d.throughput = other.throughput
+    }
+    if d.stream == "" {
+        d.stream = other.stream
+    }
+    if d.ttl == "" {
+        d.ttl = other.ttl
+    }
+
+    for name, attributeType := range other.attributes {
+        if current, ok := d.attributes[name]; ok && current != attributeType {
+            return fmt.Errorf("attribute %q is both %s and %s", name, current, attributeType)
+        }
+        d.attributes[name] = attributeType
+    }
+    for name, index := range other.global {
+        if current, ok := d.global[name]; ok && !reflect.DeepEqual(current, index) {
+            return fmt.Errorf("index %q is defined twice", name)
+        }
+        d.global[name] = index
+    }
+    for name, index := range other.local {
+        if current, ok := d.local[name]; ok && !reflect.DeepEqual(current, index) {
+            return fmt.Errorf("index %q is defined twice", name)
+        }
+        d.local[name] = index
+    }
+    return nil
+}
+
+func (d *tableDefinition) createTableInput() *dynamodb.CreateTableInput {
+    input := &dynamodb.CreateTableInput{
+        TableName:   aws.String(d.name),
+        KeySchema:   keySchema(d.partitionKey, d.sortKey),
+        BillingMode: types.BillingModePayPerRequest,
+    }
+    if d.throughput != nil {
+        input.BillingMode = types.BillingModeProvisioned
+        input.ProvisionedThroughput = d.throughput
+    }
+    if d.stream != "" {
+        input.StreamSpecification = &types.StreamSpecification{StreamEnabled: aws.Bool(true), StreamViewType: d.stream}
+    }
+
+    for _, name := range sortedKeys(d.attributes) {
+        input.AttributeDefinitions = append(input.AttributeDefinitions, types.AttributeDefinition{
+            AttributeName: aws.String(name),
+            AttributeType: d.attributes[name],
+        })
+    }
+    for _, name := range sortedKeys(d.global) {
+        index := d.global[name]
+        index.ProvisionedThroughput = d.throughput
+        input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, index)
+    }
+    for _, name := range sortedKeys(d.local) {
+        input.LocalSecondaryIndexes = append(input.LocalSecondaryIndexes, d.local[name])
+    }
+    return input
+}
+
+// check reports an existing table whose keys or indexes differ from the
+// definition.
+func (d *tableDefinition) check(table *types.TableDescription) error {
+    if !reflect.DeepEqual(keyNames(table.KeySchema), keyNames(keySchema(d.partitionKey, d.sortKey))) {
+        return fmt.Errorf("table exists with key schema %v", keyNames(table.KeySchema))
+    }
+
+    indexes := make(map[string]bool)
+    for _, index := range table.GlobalSecondaryIndexes {
+        indexes[aws.ToString(index.IndexName)] = true
+    }
+    for _, index := range table.LocalSecondaryIndexes {
+        indexes[aws.ToString(index.IndexName)] = true
+    }
+    for _, name := range append(sortedKeys(d.global), sortedKeys(d.local)...) {
+        if !indexes[name] {
+            return fmt.Errorf("table exists without index %q", name)
+        }
+    }
+    return nil
+}
+
+func keySchema(partitionKey, sortKey string) []types.KeySchemaElement {
+    schema := []types.KeySchemaElement{{AttributeName: aws.String(partitionKey), KeyType: types.KeyTypeHash}}
+    if sortKey != "" {
+        schema = append(schema, types.KeySchemaElement{AttributeName: aws.String(sortKey), KeyType: types.KeyTypeRange})
+    }
+    return schema
+}
+
+// keyNames renders a key schema as "name:HASH" pairs.
+func keyNames(schema []types.KeySchemaElement) []string {
+    names := make([]string, len(schema))
+    for i, element := range schema {
+        names[i] = aws.ToString(element.AttributeName) + ":" + string(element.KeyType)
+    }
+    sort.Strings(names)
+    return names
+}
+
+func indexProjection(attributes []string) *types.Projection {
+    if attributes == nil {
+        return &types.Projection{ProjectionType: types.ProjectionTypeAll}
+    }
+    if len(attributes) == 0 {
+        return &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly}
+    }
+    return &types.Projection{ProjectionType: types.ProjectionTypeInclude, NonKeyAttributes: attributes}
+}
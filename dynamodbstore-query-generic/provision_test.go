@@ -0,0 +1,172 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+// Describes the table input creates, in status
+func describedTable(input *dynamodb.CreateTableInput, status types.TableStatus) *dynamodb.DescribeTableOutput {
+    table := &types.TableDescription{TableName: input.TableName, TableStatus: status, KeySchema: input.KeySchema}
+    for _, index := range input.GlobalSecondaryIndexes {
+        table.GlobalSecondaryIndexes = append(table.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+            IndexName:   index.IndexName,
+            IndexStatus: types.IndexStatusActive,
+        })
+    }
+    for _, index := range input.LocalSecondaryIndexes {
+        table.LocalSecondaryIndexes = append(table.LocalSecondaryIndexes, types.LocalSecondaryIndexDescription{IndexName: index.IndexName})
+    }
+    return &dynamodb.DescribeTableOutput{Table: table}
+}
+
+func newProvisioner(mockClient *MockDynamoDBClient) *Provisioner {
+    return &Provisioner{Client: mockClient, PollInterval: time.Millisecond}
+}
+
+func TestProvisionCreatesTable(t *testing.T) {
+    ctx := context.Background()
+    registry := NewRegistry()
+    assert.NoError(t, Register[JoinToken](registry, Schema{
+        Table:        "JoinTokensTable",
+        PartitionKey: "Token",
+        Indexes: []Index{
+            {Name: "ByExpiry", SortKey: "ExpiresAt", Local: true, Projection: []string{}},
+            {Name: "ExpiryIndex", PartitionKey: "ExpiresAt", Projection: []string{"Token"}},
+        },
+        Throughput:   &types.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(5), WriteCapacityUnits: aws.Int64(5)},
+        Stream:       types.StreamViewTypeNewAndOldImages,
+        TTLAttribute: "ExpiresAtEpoch",
+    }))
+
+    definitions, err := registry.tableDefinitions()
+    assert.NoError(t, err)
+    input := definitions[0].createTableInput()
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("CreateTable", ctx, mock.Anything).Return(&dynamodb.CreateTableOutput{}, nil)
+    mockClient.On("DescribeTable", ctx, mock.Anything).Return(describedTable(input, types.TableStatusCreating), nil).Once()
+    mockClient.On("DescribeTable", ctx, mock.Anything).Return(describedTable(input, types.TableStatusActive), nil).Once()
+    mockClient.On("DescribeTimeToLive", ctx, mock.Anything).Return(&dynamodb.DescribeTimeToLiveOutput{
+        TimeToLiveDescription: &types.TimeToLiveDescription{TimeToLiveStatus: types.TimeToLiveStatusDisabled},
+    }, nil)
+    mockClient.On("UpdateTimeToLive", ctx, mock.Anything).Return(&dynamodb.UpdateTimeToLiveOutput{}, nil)
+
+    assert.NoError(t, newProvisioner(mockClient).Provision(ctx, registry))
+    mockClient.AssertExpectations(t)
+
+    created := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.CreateTableInput)
+    assert.Equal(t, "JoinTokensTable", *created.TableName)
+    assert.Equal(t, []string{"Token:HASH"}, keyNames(created.KeySchema))
+    assert.Equal(t, types.BillingModeProvisioned, created.BillingMode)
+    assert.Equal(t, types.StreamViewTypeNewAndOldImages, created.StreamSpecification.StreamViewType)
+    assert.Equal(t, []types.AttributeDefinition{
+        {AttributeName: aws.String("ExpiresAt"), AttributeType: types.ScalarAttributeTypeS},
+        {AttributeName: aws.String("Token"), AttributeType: types.ScalarAttributeTypeS},
+    }, created.AttributeDefinitions)
+
+    assert.Len(t, created.LocalSecondaryIndexes, 1)
+    assert.Equal(t, []string{"ExpiresAt:RANGE", "Token:HASH"}, keyNames(created.LocalSecondaryIndexes[0].KeySchema))
+    assert.Equal(t, types.ProjectionTypeKeysOnly, created.LocalSecondaryIndexes[0].Projection.ProjectionType)
+    assert.Len(t, created.GlobalSecondaryIndexes, 1)
+    assert.Equal(t, types.ProjectionTypeInclude, created.GlobalSecondaryIndexes[0].Projection.ProjectionType)
+    assert.Equal(t, created.ProvisionedThroughput, created.GlobalSecondaryIndexes[0].ProvisionedThroughput)
+
+    ttl := mockClient.Calls[4].Arguments.Get(1).(*dynamodb.UpdateTimeToLiveInput)
+    assert.Equal(t, "ExpiresAtEpoch", *ttl.TimeToLiveSpecification.AttributeName)
+    assert.True(t, *ttl.TimeToLiveSpecification.Enabled)
+}
+
+func TestProvisionExistingTable(t *testing.T) {
+    ctx := context.Background()
+    registry := NewRegistry()
+    assert.NoError(t, Register[JoinToken](registry, Schema{Table: "JoinTokensTable", PartitionKey: "Token", TTLAttribute: "ExpiresAtEpoch"}))
+    definitions, err := registry.tableDefinitions()
+    assert.NoError(t, err)
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("CreateTable", ctx, mock.Anything).Return((*dynamodb.CreateTableOutput)(nil), &types.ResourceInUseException{Message: aws.String("table exists")})
+    mockClient.On("DescribeTable", ctx, mock.Anything).Return(describedTable(definitions[0].createTableInput(), types.TableStatusActive), nil)
+    mockClient.On("DescribeTimeToLive", ctx, mock.Anything).Return(&dynamodb.DescribeTimeToLiveOutput{
+        TimeToLiveDescription: &types.TimeToLiveDescription{TimeToLiveStatus: types.TimeToLiveStatusEnabled, AttributeName: aws.String("ExpiresAtEpoch")},
+    }, nil)
+
+    assert.NoError(t, newProvisioner(mockClient).Provision(ctx, registry))
+    mockClient.AssertNotCalled(t, "UpdateTimeToLive", mock.Anything, mock.Anything)
+
+    // An existing table must have the keys of the schema
+    other := NewRegistry()
+    assert.NoError(t, Register[JoinToken](other, Schema{Table: "JoinTokensTable", PartitionKey: "Token", SortKey: "ExpiresAt"}))
+    err = newProvisioner(mockClient).Provision(ctx, other)
+    assert.ErrorContains(t, err, "key schema")
+}
+
+func TestProvisionSharedTable(t *testing.T) {
+    ctx := context.Background()
+    table := newSpireTable(t)
+    table.TypeIndex = "EntityTypeIndex"
+    registry := NewRegistry()
+    assert.NoError(t, Register[Bundle](registry, Schema{SingleTable: table}))
+    assert.NoError(t, Register[Entry](registry, Schema{SingleTable: table}))
+
+    definitions, err := registry.tableDefinitions()
+    assert.NoError(t, err)
+    assert.Len(t, definitions, 1)
+    input := definitions[0].createTableInput()
+    assert.Equal(t, types.BillingModePayPerRequest, input.BillingMode)
+    assert.Equal(t, []string{"PK:HASH", "SK:RANGE"}, keyNames(input.KeySchema))
+    assert.Len(t, input.AttributeDefinitions, 3)
+    assert.Equal(t, "EntityTypeIndex", *input.GlobalSecondaryIndexes[0].IndexName)
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("CreateTable", ctx, mock.Anything).Return(&dynamodb.CreateTableOutput{}, nil)
+    mockClient.On("DescribeTable", ctx, mock.Anything).Return(describedTable(input, types.TableStatusActive), nil)
+    assert.NoError(t, newProvisioner(mockClient).Provision(ctx, registry))
+    mockClient.AssertNumberOfCalls(t, "CreateTable", 1)
+
+    // Schemas of one table must agree on its settings
+    conflicting := NewRegistry()
+    assert.NoError(t, Register[Bundle](conflicting, Schema{Table: "SharedTable", PartitionKey: "ID"}))
+    assert.NoError(t, Register[Entry](conflicting, Schema{Table: "SharedTable", PartitionKey: "SpiffeID"}))
+    _, err = conflicting.tableDefinitions()
+    assert.Error(t, err)
+}
+
+func TestProvisionSharedTableMixedSettings(t *testing.T) {
+    ctx := context.Background()
+    table := newSpireTable(t)
+    assert.NoError(t, RegisterEntity[ExpiringJoinToken](table, Entity{Type: "JoinToken", PartitionKey: "TOKEN#{Token}", SortKey: "META"}))
+    registry := NewRegistry()
+    assert.NoError(t, Register[Bundle](registry, Schema{SingleTable: table}))
+    assert.NoError(t, Register[ExpiringJoinToken](registry, Schema{SingleTable: table, Stream: types.StreamViewTypeKeysOnly}))
+
+    // Settings left unset by Bundle come from the token entity
+    definitions, err := registry.tableDefinitions()
+    assert.NoError(t, err)
+    assert.Len(t, definitions, 1)
+    assert.Equal(t, "TTL", definitions[0].ttl)
+    input := definitions[0].createTableInput()
+    assert.Equal(t, types.StreamViewTypeKeysOnly, input.StreamSpecification.StreamViewType)
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("CreateTable", ctx, mock.Anything).Return(&dynamodb.CreateTableOutput{}, nil)
+    mockClient.On("DescribeTable", ctx, mock.Anything).Return(describedTable(input, types.TableStatusActive), nil)
+    mockClient.On("DescribeTimeToLive", ctx, mock.Anything).Return(&dynamodb.DescribeTimeToLiveOutput{}, nil)
+    mockClient.On("UpdateTimeToLive", ctx, mock.Anything).Return(&dynamodb.UpdateTimeToLiveOutput{}, nil)
+    assert.NoError(t, newProvisioner(mockClient).Provision(ctx, registry))
+    mockClient.AssertNumberOfCalls(t, "UpdateTimeToLive", 1)
+
+    // Two settings given differently still conflict
+    conflicting := NewRegistry()
+    assert.NoError(t, Register[Bundle](conflicting, Schema{SingleTable: table, Stream: types.StreamViewTypeNewImage}))
+    assert.NoError(t, Register[ExpiringJoinToken](conflicting, Schema{SingleTable: table, Stream: types.StreamViewTypeKeysOnly}))
+    _, err = conflicting.tableDefinitions()
+    assert.ErrorContains(t, err, "different stream")
+}
@@ -0,0 +1,430 @@
+package dynamodbstore
+
+import (
+    "context"
+    "fmt"
+    "reflect"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Schema describes where and how the items of one Go type are stored.
+type Schema struct {
+    Table        string
+    PartitionKey string
+    SortKey      string
+    Indexes      []Index
+
+    // Attributes overrides the type of attributes whose Go field does not
+    // tell it, such as fields with a custom marshaller.
+    Attributes map[string]types.ScalarAttributeType
+
+    // SingleTable, when set, stores the type as an Entity of that table;
+    // Table, PartitionKey and SortKey then come from it.
+    SingleTable *SingleTable
+
+    // The remaining fields only matter to Provision. Throughput selects
+    // provisioned billing, on-demand otherwise; Stream enables a stream with
+    // that view and TTLAttribute enables Time to Live on that attribute,
+    // which defaults to the one of a field tagged `dynamodbstore:"ttl"`.
+    Throughput   *types.ProvisionedThroughput
+    Stream       types.StreamViewType
+    TTLAttribute string
+}
+
+// Index is a secondary index of a Schema. A Local index shares the table's
+// partition key, so its PartitionKey is left empty. Projection lists the
+// non-key attributes the index holds: nil projects them all and an empty
+// slice only the keys.
+type Index struct {
+    Name         string
+    PartitionKey string
+    SortKey      string
+    Local        bool
+    Projection   []string
+}
+
+// Registry maps Go types to their Schema, so ListItemsFor and NewStoreFor
+// need no table or key names.
+type Registry struct {
+    mu      sync.RWMutex
+    schemas map[reflect.Type]*schema
+}
+
+func NewRegistry() *Registry {
+    return &Registry{schemas: make(map[reflect.Type]*schema)}
+}
+
+// schema is a validated Schema with the attributes of its type.
+type schema struct {
+    Schema
+    itemType   reflect.Type
+    attributes map[string]reflect.Type
+    times      timeCodec
+}
+
+// Register records the Schema of T. It fails when T is already registered
+// or when a key or index names an attribute T does not have.
+func Register[T any](registry *Registry, s Schema) error {
+    itemType := reflect.TypeOf((*T)(nil)).Elem()
+    if itemType.Kind() != reflect.Struct {
+        return fmt.Errorf("cannot register %v: only structs have a schema", itemType)
+    }
+
+    times, err := timeCodecOf(itemType)
+    if err != nil {
+        return err
+    }
+    registered := &schema{Schema: s, itemType: itemType, attributes: itemFieldTypes(itemType), times: times}
+    ttl, err := findTTLField(itemType)
+    if err != nil {
+        return err
+    }
+    if ttl != nil {
+        if s.TTLAttribute != "" && s.TTLAttribute != ttl.epoch {
+            return fmt.Errorf("invalid schema for %v: TTLAttribute %q differs from its ttl field attribute %q", itemType, s.TTLAttribute, ttl.epoch)
+        }
+        registered.TTLAttribute = ttl.epoch
+        registered.attributes[ttl.epoch] = reflect.TypeOf(int64(0))
+    }
+    if s.SingleTable != nil {
+        if _, err := s.SingleTable.entity(itemType); err != nil {
+            return err
+        }
+        if len(s.Indexes) > 0 {
+            return fmt.Errorf("invalid schema for %v: the indexes of a single table come from its TypeIndex", itemType)
+        }
+        registered.Table = s.SingleTable.Name
+        registered.PartitionKey = s.SingleTable.PartitionKey
+        registered.SortKey = s.SingleTable.SortKey
+    } else if err := registered.validateKeys(); err != nil {
+        return fmt.Errorf("invalid schema for %v: %w", itemType, err)
+    }
+
+    registry.mu.Lock()
+    defer registry.mu.Unlock()
+    if _, ok := registry.schemas[itemType]; ok {
+        return fmt.Errorf("%v is already registered", itemType)
+    }
+    registry.schemas[itemType] = registered
+    return nil
+}
+
+func (r *Registry) lookup(itemType reflect.Type) (*schema, error) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    registered, ok := r.schemas[itemType]
+    if !ok {
+        return nil, fmt.Errorf("%v is not registered", itemType)
+    }
+    return registered, nil
+}
+
+func lookup[T any](registry *Registry) (*schema, error) {
+    return registry.lookup(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func (s *schema) validateKeys() error {
+    if s.Table == "" {
+        return fmt.Errorf("no table")
+    }
+    if s.PartitionKey == "" {
+        return fmt.Errorf("no partition key")
+    }
+    if err := s.validateKey(s.PartitionKey, s.SortKey); err != nil {
+        return err
+    }
+
+    names := make(map[string]bool)
+    for _, index := range s.Indexes {
+        if index.Name == "" || names[index.Name] {
+            return fmt.Errorf("index names must be unique and not empty, got %q", index.Name)
+        }
+        names[index.Name] = true
+
+        partitionKey := index.PartitionKey
+        if index.Local {
+            if partitionKey != "" && partitionKey != s.PartitionKey {
+                return fmt.Errorf("local index %q must use the table partition key", index.Name)
+            }
+            if index.SortKey == "" {
+                return fmt.Errorf("local index %q needs a sort key", index.Name)
+            }
+            partitionKey = s.PartitionKey
+        }
+        if partitionKey == "" {
+            return fmt.Errorf("index %q has no partition key", index.Name)
+        }
+        if err := s.validateKey(partitionKey, index.SortKey); err != nil {
+            return fmt.Errorf("index %q: %w", index.Name, err)
+        }
+    }
+    return nil
+}
+
+func (s *schema) validateKey(names ...string) error {
+    for _, name := range names {
+        if name == "" {
+            continue
+        }
+        if _, err := s.keyType(name); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// keyType is the scalar type of a key attribute.
+func (s *schema) keyType(name string) (types.ScalarAttributeType, error) {
+    if attributeType, ok := s.Attributes[name]; ok {
+        return attributeType, nil
+    }
+    field, ok := s.attributes[name]
+    if !ok {
+        return "", fmt.Errorf("key %q is not an attribute of %v", name, s.itemType)
+    }
+    if attributeType, ok := s.times.scalarType(name); ok {
+        return attributeType, nil
+    }
+    attributeType, ok := scalarType(field)
+    if !ok {
+        return "", fmt.Errorf("key %q of type %v is not a string, number or binary; set its type in Attributes", name, field)
+    }
+    return attributeType, nil
+}
+
+// keys returns the partition and sort key of the table, or of index.
+func (s *schema) keys(index string) (partitionKey, sortKey string, err error) {
+    if index == "" {
+        return s.PartitionKey, s.SortKey, nil
+    }
+    for _, i := range s.Indexes {
+        if i.Name != index {
+            continue
+        }
+        if i.Local {
+            return s.PartitionKey, i.SortKey, nil
+        }
+        return i.PartitionKey, i.SortKey, nil
+    }
+    return "", "", fmt.Errorf("%v has no index %q", s.itemType, index)
+}
+
+// ListItemsFor is ListItems with the table, keys and index taken from the
+// Schema registered for T. Filters and projection are checked against the
+// attributes of T first; WithIndex names one of the Schema's Indexes.
+func ListItemsFor[T any](
+    ctx context.Context,
+    registry *Registry,
+    dynamoClient dynamoQueryClient,
+    filters []Filter,
+    pagination *Pagination,
+    projection []string,
+    opts ...Option,
+) ([]T, *Pagination, error) {
+
+    s, err := lookup[T](registry)
+    if err != nil {
+        return nil, nil, err
+    }
+    if err := s.validateFilters(filters); err != nil {
+        return nil, nil, fmt.Errorf("invalid filters: %w", err)
+    }
+    if err := s.validateProjection(projection); err != nil {
+        return nil, nil, err
+    }
+
+    if s.SingleTable != nil {
+        return ListEntities[T](ctx, s.SingleTable, dynamoClient, filters, pagination, projection, opts...)
+    }
+    partitionKey, sortKey, err := s.keys(newOptions(opts).index)
+    if err != nil {
+        return nil, nil, err
+    }
+    return ListItems[T](ctx, s.Table, dynamoClient, partitionKey, sortKey, filters, pagination, projection, opts...)
+}
+
+// NewStoreFor returns the Store of the Schema registered for T.
+func NewStoreFor[T any](registry *Registry, client dynamoStoreClient) (*Store[T], error) {
+    s, err := lookup[T](registry)
+    if err != nil {
+        return nil, err
+    }
+    if s.SingleTable != nil {
+        return NewEntityStore[T](s.SingleTable, client)
+    }
+    return NewStore[T](s.Table, client, s.PartitionKey, s.SortKey)
+}
+
+func (s *schema) validateProjection(projection []string) error {
+    for _, name := range projection {
+        if _, ok := s.attributes[topLevelAttribute(name)]; !ok {
+            return fmt.Errorf("projection %q is not an attribute of %v", name, s.itemType)
+        }
+    }
+    return nil
+}
+
+// validateFilters checks that every filter names an attribute of the type
+// and that its operation and value suit the attribute's Go type.
+func (s *schema) validateFilters(filters []Filter) error {
+    for _, filter := range filters {
+        if filter.Combinator != 0 {
+            if err := s.validateFilters(filter.Filters); err != nil {
+                return err
+            }
+            continue
+        }
+
+        field, ok := s.attributes[topLevelAttribute(filter.Name)]
+        if !ok {
+            return fmt.Errorf("%q is not an attribute of %v", filter.Name, s.itemType)
+        }
+        if topLevelAttribute(filter.Name) != filter.Name {
+            // Nested paths are not described by the schema
+            continue
+        }
+        if err := validateFilterValue(filter, field); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func validateFilterValue(filter Filter, field reflect.Type) error {
+    kind := valueKindOf(field)
+
+    switch filter.Op {
+    case AttributeExists, AttributeNotExists, AttributeType, Size:
+        return nil
+    case MatchAny, MatchSuperset, MatchSubset:
+        if kind == kindNumber || kind == kindBool || kind == kindTime {
+            return fmt.Errorf("filter on %q needs a list, set, map or string attribute, %v is not", filter.Name, field)
+        }
+        return nil
+    case BeginsWith:
+        if kind != kindString && kind != kindOther {
+            return fmt.Errorf("begins with filter on %q needs a string attribute, %v is not", filter.Name, field)
+        }
+        return nil
+    case LessThan, LessOrEqual, GreaterThan, GreaterOrEqual, Between:
+        if kind == kindBool || kind == kindCollection {
+            return fmt.Errorf("%v attribute %q cannot be ordered", field, filter.Name)
+        }
+    }
+
+    var values []interface{}
+    switch filter.Op {
+    case Between:
+        if bounds, ok := filter.Value.(Range); ok {
+            values = []interface{}{bounds.Lower, bounds.Upper}
+        }
+    case In:
+        values, _ = setValues(filter)
+    default:
+        values = []interface{}{filter.Value}
+    }
+    for _, value := range values {
+        if !compatibleValue(kind, value) {
+            return fmt.Errorf("filter on %q compares a %v attribute with a %T value", filter.Name, field, value)
+        }
+    }
+    return nil
+}
+
+// valueKind groups Go types by the DynamoDB values they marshal to.
+type valueKind int
+
+const (
+    kindOther valueKind = iota
+    kindString
+    kindNumber
+    kindBool
+    kindBinary
+    kindTime
+    kindCollection
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func valueKindOf(t reflect.Type) valueKind {
+    for t.Kind() == reflect.Pointer {
+        t = t.Elem()
+    }
+    if t == timeType {
+        return kindTime
+    }
+
+    switch t.Kind() {
+    case reflect.String:
+        return kindString
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+        reflect.Float32, reflect.Float64:
+        return kindNumber
+    case reflect.Bool:
+        return kindBool
+    case reflect.Slice, reflect.Array:
+        if t.Elem().Kind() == reflect.Uint8 {
+            return kindBinary
+        }
+        return kindCollection
+    case reflect.Map:
+        return kindCollection
+    }
+    return kindOther
+}
+
+// compatibleValue tells whether value can be compared with an attribute of
+// kind. Types the schema cannot judge are accepted.
+func compatibleValue(kind valueKind, value interface{}) bool {
+    if value == nil || kind == kindOther {
+        return true
+    }
+    valueKind := valueKindOf(reflect.TypeOf(value))
+
+    switch {
+    case valueKind == kindOther || valueKind == kind:
+        return true
+    case kind == kindTime:
+        // Times are stored as strings unless encoded otherwise
+        return valueKind == kindString || valueKind == kindNumber
+    }
+    return false
+}
+
+// scalarType is the key attribute type of a Go type.
+func scalarType(t reflect.Type) (types.ScalarAttributeType, bool) {
+    switch valueKindOf(t) {
+    case kindString, kindTime:
+        return types.ScalarAttributeTypeS, true
+    case kindNumber:
+        return types.ScalarAttributeTypeN, true
+    case kindBinary:
+        return types.ScalarAttributeTypeB, true
+    }
+    return "", false
+}
+
+// topLevelAttribute strips the nested path from an attribute name.
+func topLevelAttribute(name string) string {
+    if i := strings.IndexAny(name, ".["); i >= 0 {
+        return name[:i]
+    }
+    return name
+}
+
+// itemFieldTypes maps the attributes attributevalue stores for itemType to
+// the Go type of their field.
+func itemFieldTypes(itemType reflect.Type) map[string]reflect.Type {
+    fields := make(map[string]reflect.Type)
+    for _, field := range reflect.VisibleFields(itemType) {
+        if name, ok := attributeName(field); ok {
+            fields[name] = field.Type
+        }
+    }
+    return fields
+}
@@ -0,0 +1,148 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func newSpireRegistry(t *testing.T) *Registry {
+    registry := NewRegistry()
+    assert.NoError(t, Register[Bundle](registry, Schema{Table: "BundlesTable", PartitionKey: "ID"}))
+    assert.NoError(t, Register[Entry](registry, Schema{
+        Table:        "EntriesTable",
+        PartitionKey: "SpiffeID",
+        Indexes:      []Index{{Name: "ParentIndex", PartitionKey: "ParentID", SortKey: "SpiffeID"}},
+    }))
+    assert.NoError(t, Register[EntryEvent](registry, Schema{Table: "EntryEventsTable", PartitionKey: "EventID", SortKey: "CreatedAt"}))
+    return registry
+}
+
+func TestRegisterValidatesSchema(t *testing.T) {
+    registry := newSpireRegistry(t)
+
+    assert.Error(t, Register[Bundle](registry, Schema{Table: "OtherTable", PartitionKey: "ID"}))
+    assert.Error(t, Register[JoinToken](registry, Schema{Table: "JoinTokensTable"}))
+    assert.Error(t, Register[JoinToken](registry, Schema{Table: "JoinTokensTable", PartitionKey: "Value"}))
+    assert.Error(t, Register[JoinToken](registry, Schema{
+        Table:        "JoinTokensTable",
+        PartitionKey: "Token",
+        Indexes:      []Index{{Name: "ByExpiry", PartitionKey: "ExpiresAt", Local: true, SortKey: "Token"}},
+    }))
+    assert.Error(t, Register[JoinToken](registry, Schema{
+        Table:        "JoinTokensTable",
+        PartitionKey: "Token",
+        Indexes:      []Index{{Name: "ByExpiry", PartitionKey: "ExpiresAt"}, {Name: "ByExpiry", PartitionKey: "Token"}},
+    }))
+    assert.Error(t, Register[int](registry, Schema{Table: "Numbers", PartitionKey: "N"}))
+
+    assert.NoError(t, Register[JoinToken](registry, Schema{
+        Table:        "JoinTokensTable",
+        PartitionKey: "Token",
+        Indexes:      []Index{{Name: "ByExpiry", SortKey: "ExpiresAt", Local: true}},
+        Attributes:   map[string]types.ScalarAttributeType{"ExpiresAt": types.ScalarAttributeTypeN},
+    }))
+
+    s, err := lookup[JoinToken](registry)
+    assert.NoError(t, err)
+    keyType, err := s.keyType("ExpiresAt")
+    assert.NoError(t, err)
+    assert.Equal(t, types.ScalarAttributeTypeN, keyType)
+    keyType, err = s.keyType("Token")
+    assert.NoError(t, err)
+    assert.Equal(t, types.ScalarAttributeTypeS, keyType)
+}
+
+func TestListItemsForUsesSchema(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{bundleItem("bundle1")}}, nil)
+    registry := newSpireRegistry(t)
+
+    bundles, _, err := ListItemsFor[Bundle](ctx, registry, mockClient, []Filter{{Name: "ID", Op: EqualTo, Value: "bundle1"}}, nil, []string{"ID", "Name"})
+    assert.NoError(t, err)
+    assert.Len(t, bundles, 1)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Equal(t, "BundlesTable", *input.TableName)
+    assert.Nil(t, input.IndexName)
+    assert.NotNil(t, input.KeyConditionExpression)
+
+    // The index keys make ParentID the key condition
+    _, _, err = ListItemsFor[Entry](ctx, registry, mockClient, []Filter{{Name: "ParentID", Op: EqualTo, Value: "spiffe://example.org/node"}}, nil, nil, WithIndex("ParentIndex"))
+    assert.NoError(t, err)
+
+    input = mockClient.Calls[1].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Equal(t, "EntriesTable", *input.TableName)
+    assert.Equal(t, "ParentIndex", *input.IndexName)
+    assert.Nil(t, input.FilterExpression)
+
+    _, _, err = ListItemsFor[Entry](ctx, registry, mockClient, nil, nil, nil, WithIndex("Missing"))
+    assert.Error(t, err)
+    _, _, err = ListItemsFor[JoinToken](ctx, registry, mockClient, nil, nil, nil)
+    assert.Error(t, err)
+    assert.Len(t, mockClient.Calls, 2)
+}
+
+func TestListItemsForValidatesFilters(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    registry := newSpireRegistry(t)
+
+    invalid := [][]Filter{
+        {{Name: "Missing", Op: EqualTo, Value: "x"}},
+        {{Name: "EventID", Op: EqualTo, Value: "1"}},
+        {{Name: "EventID", Op: BeginsWith, Value: "1"}},
+        {{Name: "EventID", Op: MatchAny, Value: []int{1}}},
+        {{Name: "EventID", Op: In, Value: []interface{}{1, "2"}}},
+        {{Name: "EventID", Op: Between, Value: Range{Lower: 1, Upper: "9"}}},
+        {Or(Filter{Name: "EventID", Op: EqualTo, Value: 1}, Filter{Name: "Missing", Op: AttributeExists})},
+    }
+    for _, filters := range invalid {
+        _, _, err := ListItemsFor[EntryEvent](ctx, registry, mockClient, filters, nil, nil)
+        assert.Error(t, err, "%+v", filters)
+    }
+    _, _, err := ListItemsFor[EntryEvent](ctx, registry, mockClient, nil, nil, []string{"Missing"})
+    assert.Error(t, err)
+    mockClient.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+    _, _, err = ListItemsFor[EntryEvent](ctx, registry, mockClient, []Filter{
+        {Name: "EventID", Op: EqualTo, Value: 1},
+        {Name: "CreatedAt", Op: GreaterThan, Value: "2024-01-01T00:00:00Z"},
+    }, nil, []string{"EventID", "CreatedAt"})
+    assert.NoError(t, err)
+}
+
+func TestNewStoreFor(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("GetItem", ctx, mock.Anything).Return(&dynamodb.GetItemOutput{Item: bundleItem("bundle1")}, nil)
+    registry := newSpireRegistry(t)
+
+    store, err := NewStoreFor[Bundle](registry, mockClient)
+    assert.NoError(t, err)
+    _, err = store.Get(ctx, Key{Partition: "bundle1"})
+    assert.NoError(t, err)
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.GetItemInput)
+    assert.Equal(t, "BundlesTable", *input.TableName)
+
+    // Single-table types resolve to their entity store
+    table := newSpireTable(t)
+    singleTable := NewRegistry()
+    assert.NoError(t, Register[Bundle](singleTable, Schema{SingleTable: table}))
+    assert.Error(t, Register[JoinToken](singleTable, Schema{SingleTable: table}))
+
+    store, err = NewStoreFor[Bundle](singleTable, mockClient)
+    assert.NoError(t, err)
+    key, err := store.KeyOf(Bundle{ID: "bundle1"})
+    assert.NoError(t, err)
+    assert.Equal(t, &types.AttributeValueMemberS{Value: "BUNDLE#bundle1"}, key.Partition)
+
+    _, err = NewStoreFor[JoinToken](registry, mockClient)
+    assert.Error(t, err)
+}
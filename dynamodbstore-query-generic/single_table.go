@@ -0,0 +1,354 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "reflect"
+    "strings"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// SingleTable is a table holding items of several types. Each type is
+// registered as an Entity whose templates build the partition and sort keys
+// from the item's own attributes, and every item stores the name of its
+// entity in TypeAttribute.
+type SingleTable struct {
+    Name          string
+    PartitionKey  string // "PK" unless set
+    SortKey       string // "SK" unless set
+    TypeAttribute string // "EntityType" unless set
+
+    // TypeIndex optionally names a global secondary index partitioned by
+    // TypeAttribute, used to list an entity across partitions.
+    TypeIndex string
+
+    entities map[reflect.Type]*entity
+}
+
+func NewSingleTable(name string) *SingleTable {
+    return &SingleTable{Name: name, PartitionKey: "PK", SortKey: "SK", TypeAttribute: "EntityType"}
+}
+
+// Entity maps a Go type to its items in a SingleTable. PartitionKey and
+// SortKey are templates where {Name} stands for the value of the item's
+// Name attribute, e.g. "BUNDLE#{TrustDomain}" and "META". Placeholders take
+// string or number attributes.
+type Entity struct {
+    Type         string
+    PartitionKey string
+    SortKey      string
+}
+
+type entity struct {
+    name      string
+    partition keyTemplate
+    sort      keyTemplate
+}
+
+// RegisterEntity registers T as entity of table.
+func RegisterEntity[T any](table *SingleTable, e Entity) error {
+    itemType := reflect.TypeOf((*T)(nil)).Elem()
+    if e.Type == "" {
+        return fmt.Errorf("entity for %v has no type name", itemType)
+    }
+    if _, ok := table.entities[itemType]; ok {
+        return fmt.Errorf("%v is already registered in %q", itemType, table.Name)
+    }
+    for other, registered := range table.entities {
+        if registered.name == e.Type {
+            return fmt.Errorf("entity type %q is already used by %v", e.Type, other)
+        }
+    }
+
+    registered := &entity{name: e.Type}
+    var err error
+    if registered.partition, err = parseKeyTemplate(e.PartitionKey); err != nil {
+        return fmt.Errorf("invalid partition key template: %w", err)
+    }
+    if registered.sort, err = parseKeyTemplate(e.SortKey); err != nil {
+        return fmt.Errorf("invalid sort key template: %w", err)
+    }
+
+    attributes := itemAttributes(itemType)
+    for _, name := range []string{table.PartitionKey, table.SortKey, table.TypeAttribute} {
+        if attributes[name] {
+            return fmt.Errorf("%v has an attribute %q reserved by table %q", itemType, name, table.Name)
+        }
+    }
+    for _, field := range append(registered.partition.fields(), registered.sort.fields()...) {
+        if !attributes[field] {
+            return fmt.Errorf("key template field %q is not an attribute of %v", field, itemType)
+        }
+    }
+
+    if table.entities == nil {
+        table.entities = make(map[reflect.Type]*entity)
+    }
+    table.entities[itemType] = registered
+    return nil
+}
+
+func (t *SingleTable) entity(itemType reflect.Type) (*entity, error) {
+    registered, ok := t.entities[itemType]
+    if !ok {
+        return nil, fmt.Errorf("%v is not registered in %q", itemType, t.Name)
+    }
+    return registered, nil
+}
+
+// entityQuery is how ListEntities reads one entity.
+type entityQuery struct {
+    partitionKey string
+    sortKey      string
+    filters      []Filter
+    opts         []Option
+}
+
+// query picks the narrowest read of e matching filters: a Query of the
+// partition the EqualTo filters on the partition template fields point to,
+// restricted to the sort key the sort template allows, or else a Query of
+// TypeIndex.
+func (t *SingleTable) query(e *entity, filters []Filter) (entityQuery, error) {
+    typeFilter := Filter{Name: t.TypeAttribute, Op: EqualTo, Value: e.name}
+
+    values, err := fixedValues(filters)
+    if err != nil {
+        return entityQuery{}, err
+    }
+    partition, ok := e.partition.render(values)
+    if !ok {
+        if t.TypeIndex == "" {
+            return entityQuery{}, fmt.Errorf("listing %s needs EqualTo filters on %s, or a TypeIndex", e.name, strings.Join(e.partition.fields(), ", "))
+        }
+        return entityQuery{
+            partitionKey: t.TypeAttribute,
+            filters:      append([]Filter{typeFilter}, filters...),
+            opts:         []Option{WithIndex(t.TypeIndex), withTableKeys(t.PartitionKey, t.SortKey)},
+        }, nil
+    }
+
+    keyFilters := []Filter{{Name: t.PartitionKey, Op: EqualTo, Value: partition}}
+    if sort, ok := e.sort.render(values); ok {
+        keyFilters = append(keyFilters, Filter{Name: t.SortKey, Op: EqualTo, Value: sort})
+    } else if prefix := e.sort.prefix(); prefix != "" {
+        keyFilters = append(keyFilters, Filter{Name: t.SortKey, Op: BeginsWith, Value: prefix})
+    }
+    keyFilters = append(keyFilters, typeFilter)
+
+    return entityQuery{
+        partitionKey: t.PartitionKey,
+        sortKey:      t.SortKey,
+        filters:      append(keyFilters, filters...),
+    }, nil
+}
+
+// ListEntities is ListItems for the items of entity T in table. The key
+// condition and entity type filter come from T's Entity, so only items of T
+// are returned; filters only need to fix the partition template fields with
+// EqualTo, or can omit them when the table has a TypeIndex.
+func ListEntities[T any](
+    ctx context.Context,
+    table *SingleTable,
+    dynamoClient dynamoQueryClient,
+    filters []Filter,
+    pagination *Pagination,
+    projection []string,
+    opts ...Option,
+) ([]T, *Pagination, error) {
+
+    e, err := table.entity(reflect.TypeOf((*T)(nil)).Elem())
+    if err != nil {
+        return nil, nil, err
+    }
+    times, err := timeCodecFor[T]()
+    if err != nil {
+        return nil, nil, err
+    }
+    query, err := table.query(e, times.encodeFilters(filters))
+    if err != nil {
+        return nil, nil, err
+    }
+    opts = append(append([]Option(nil), opts...), query.opts...)
+    return ListItems[T](ctx, table.Name, dynamoClient, query.partitionKey, query.sortKey, query.filters, pagination, projection, opts...)
+}
+
+// NewEntityStore returns a Store for entity T of table. Its items are
+// written with their rendered keys and entity type, and its keys are the
+// rendered key values; use KeyOf to build them from an item.
+func NewEntityStore[T any](table *SingleTable, client dynamoStoreClient) (*Store[T], error) {
+    itemType := reflect.TypeOf((*T)(nil)).Elem()
+    e, err := table.entity(itemType)
+    if err != nil {
+        return nil, err
+    }
+
+    store, err := NewStore[T](table.Name, client, table.PartitionKey, table.SortKey)
+    if err != nil {
+        return nil, err
+    }
+    store.table, store.entity = table, e
+    return store, nil
+}
+
+// KeyOf returns the key of item.
+func (s *Store[T]) KeyOf(item T) (Key, error) {
+    record, err := s.marshalItem(item)
+    if err != nil {
+        return Key{}, err
+    }
+    key := Key{Partition: record[s.partitionKey]}
+    if s.sortKey != "" {
+        key.Sort = record[s.sortKey]
+    }
+    return key, nil
+}
+
+// managed tells whether attribute is derived from or part of the keys of e.
+func (e *entity) managed(table *SingleTable, attribute string) bool {
+    for _, name := range append(e.partition.fields(), e.sort.fields()...) {
+        if name == attribute {
+            return true
+        }
+    }
+    return attribute == table.TypeAttribute
+}
+
+// entityAttributes adds the rendered keys and entity type to record.
+func (s *Store[T]) entityAttributes(record map[string]types.AttributeValue) error {
+    partition, ok := s.entity.partition.render(record)
+    if !ok {
+        return fmt.Errorf("record is missing a field of partition key template %q", s.entity.partition)
+    }
+    sort, ok := s.entity.sort.render(record)
+    if !ok {
+        return fmt.Errorf("record is missing a field of sort key template %q", s.entity.sort)
+    }
+
+    record[s.table.PartitionKey] = &types.AttributeValueMemberS{Value: partition}
+    record[s.table.SortKey] = &types.AttributeValueMemberS{Value: sort}
+    record[s.table.TypeAttribute] = &types.AttributeValueMemberS{Value: s.entity.name}
+    return nil
+}
+
+// typeCondition holds when the stored item is of the store's entity, so a
+// key rendered for another entity does not change its item.
+func (s *Store[T]) typeCondition() expression.ConditionBuilder {
+    return expression.Name(s.table.TypeAttribute).Equal(expression.Value(s.entity.name))
+}
+
+// foreign tells whether record, returned by a failed condition, is an item
+// of another entity. To an entity store such an item does not exist.
+func (s *Store[T]) foreign(record map[string]types.AttributeValue) bool {
+    if s.entity == nil || record == nil {
+        return false
+    }
+    name, ok := record[s.table.TypeAttribute].(*types.AttributeValueMemberS)
+    return !ok || name.Value != s.entity.name
+}
+
+// keyTemplate is a parsed key template: literal text and {attribute}
+// placeholders.
+type keyTemplate struct {
+    source string
+    parts  []templatePart
+}
+
+type templatePart struct {
+    literal string
+    field   string
+}
+
+func (t keyTemplate) String() string { return t.source }
+
+func parseKeyTemplate(source string) (keyTemplate, error) {
+    template := keyTemplate{source: source}
+    if source == "" {
+        return template, errors.New("empty template")
+    }
+
+    for rest := source; rest != ""; {
+        open := strings.IndexAny(rest, "{}")
+        if open < 0 {
+            template.parts = append(template.parts, templatePart{literal: rest})
+            break
+        }
+        if rest[open] == '}' {
+            return template, fmt.Errorf("unexpected } in %q", source)
+        }
+        if open > 0 {
+            template.parts = append(template.parts, templatePart{literal: rest[:open]})
+        }
+
+        field, after, ok := strings.Cut(rest[open+1:], "}")
+        if !ok || field == "" || strings.Contains(field, "{") {
+            return template, fmt.Errorf("malformed placeholder in %q", source)
+        }
+        template.parts = append(template.parts, templatePart{field: field})
+        rest = after
+    }
+    return template, nil
+}
+
+func (t keyTemplate) fields() []string {
+    var fields []string
+    for _, part := range t.parts {
+        if part.field != "" {
+            fields = append(fields, part.field)
+        }
+    }
+    return fields
+}
+
+// prefix is the literal text before the first placeholder.
+func (t keyTemplate) prefix() string {
+    var prefix strings.Builder
+    for _, part := range t.parts {
+        if part.field != "" {
+            break
+        }
+        prefix.WriteString(part.literal)
+    }
+    return prefix.String()
+}
+
+// render fills the placeholders from values; ok is false when one of them
+// is missing or is not a string or number.
+func (t keyTemplate) render(values map[string]types.AttributeValue) (string, bool) {
+    var rendered strings.Builder
+    for _, part := range t.parts {
+        if part.field == "" {
+            rendered.WriteString(part.literal)
+            continue
+        }
+        switch v := values[part.field].(type) {
+        case *types.AttributeValueMemberS:
+            rendered.WriteString(v.Value)
+        case *types.AttributeValueMemberN:
+            rendered.WriteString(v.Value)
+        default:
+            return "", false
+        }
+    }
+    return rendered.String(), true
+}
+
+// fixedValues collects the values of the top-level EqualTo filters, which
+// fix the attribute for every returned item.
+func fixedValues(filters []Filter) (map[string]types.AttributeValue, error) {
+    values := make(map[string]types.AttributeValue)
+    for _, filter := range filters {
+        if filter.Combinator != 0 || filter.Op != EqualTo {
+            continue
+        }
+        value, err := attributevalue.Marshal(filter.Value)
+        if err != nil {
+            return nil, fmt.Errorf("invalid value for %q: %w", filter.Name, err)
+        }
+        values[filter.Name] = value
+    }
+    return values, nil
+}
@@ -0,0 +1,231 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func newSpireTable(t *testing.T) *SingleTable {
+    table := NewSingleTable("SpireTable")
+    assert.NoError(t, RegisterEntity[Bundle](table, Entity{Type: "Bundle", PartitionKey: "BUNDLE#{ID}", SortKey: "META"}))
+    assert.NoError(t, RegisterEntity[Entry](table, Entity{Type: "Entry", PartitionKey: "PARENT#{ParentID}", SortKey: "ENTRY#{SpiffeID}"}))
+    return table
+}
+
+// Returns the values of the expression attribute values of type S
+func stringValues(input *dynamodb.QueryInput) []string {
+    var values []string
+    for _, value := range input.ExpressionAttributeValues {
+        if s, ok := value.(*types.AttributeValueMemberS); ok {
+            values = append(values, s.Value)
+        }
+    }
+    return values
+}
+
+func TestListEntitiesKeyCondition(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+        "PK":         &types.AttributeValueMemberS{Value: "BUNDLE#example.org"},
+        "SK":         &types.AttributeValueMemberS{Value: "META"},
+        "EntityType": &types.AttributeValueMemberS{Value: "Bundle"},
+        "ID":         &types.AttributeValueMemberS{Value: "example.org"},
+    }}}, nil).Once()
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+    table := newSpireTable(t)
+
+    bundles, _, err := ListEntities[Bundle](ctx, table, mockClient, []Filter{{Name: "ID", Op: EqualTo, Value: "example.org"}}, nil, nil)
+    assert.NoError(t, err)
+    assert.Equal(t, []Bundle{{ID: "example.org"}}, bundles)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.ElementsMatch(t, []string{"PK", "SK", "EntityType", "ID"}, attributeNames(input))
+    assert.Subset(t, stringValues(input), []string{"BUNDLE#example.org", "META", "Bundle"})
+    assert.NotNil(t, input.FilterExpression)
+
+    // A sort template with an unknown field narrows to its literal prefix
+    _, _, err = ListEntities[Entry](ctx, table, mockClient, []Filter{{Name: "ParentID", Op: EqualTo, Value: "spiffe://example.org/node"}}, nil, nil)
+    assert.NoError(t, err)
+
+    input = mockClient.Calls[1].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Contains(t, *input.KeyConditionExpression, "begins_with")
+    assert.Subset(t, stringValues(input), []string{"PARENT#spiffe://example.org/node", "ENTRY#", "Entry"})
+}
+
+func TestListEntitiesTypeIndex(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+    table := newSpireTable(t)
+
+    _, _, err := ListEntities[Bundle](ctx, table, mockClient, nil, nil, nil)
+    assert.Error(t, err)
+    mockClient.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+
+    table.TypeIndex = "EntityTypeIndex"
+    _, _, err = ListEntities[Bundle](ctx, table, mockClient, nil, nil, nil)
+    assert.NoError(t, err)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Equal(t, "EntityTypeIndex", *input.IndexName)
+    assert.Equal(t, []string{"EntityType"}, attributeNames(input))
+    assert.Nil(t, input.FilterExpression)
+
+    _, _, err = ListEntities[JoinToken](ctx, table, mockClient, nil, nil, nil)
+    assert.Error(t, err)
+}
+
+func TestListEntitiesTypeIndexFillKeepsTableKeys(t *testing.T) {
+    ctx := context.Background()
+    key := func(id string) map[string]types.AttributeValue {
+        return map[string]types.AttributeValue{
+            "EntityType": &types.AttributeValueMemberS{Value: "Bundle"},
+            "PK":         &types.AttributeValueMemberS{Value: "BUNDLE#" + id},
+            "SK":         &types.AttributeValueMemberS{Value: "META"},
+        }
+    }
+    item := func(id string) map[string]types.AttributeValue {
+        item := key(id)
+        item["ID"] = &types.AttributeValueMemberS{Value: id}
+        return item
+    }
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item("a")}, LastEvaluatedKey: key("a")}, nil).Once()
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item("b"), item("c")}}, nil).Once()
+    table := newSpireTable(t)
+    table.TypeIndex = "EntityTypeIndex"
+
+    pagination := &Pagination{Limit: 2, Fill: true}
+    bundles, pagination, err := ListEntities[Bundle](ctx, table, mockClient, nil, pagination, []string{"ID"})
+    assert.NoError(t, err)
+    assert.Equal(t, []Bundle{{ID: "a"}, {ID: "b"}}, bundles)
+    assert.NotEmpty(t, pagination.NextToken)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.ElementsMatch(t, []string{"EntityType", "PK", "SK", "ID"}, attributeNames(input))
+
+    // The cut page resumes from the index and table keys of its last item
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+    pagination.Token = pagination.NextToken
+    _, _, err = ListEntities[Bundle](ctx, table, mockClient, nil, pagination, []string{"ID"})
+    assert.NoError(t, err)
+    assert.Equal(t, key("b"), mockClient.Calls[2].Arguments.Get(1).(*dynamodb.QueryInput).ExclusiveStartKey)
+}
+
+func TestEntityStore(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("PutItem", ctx, mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+
+    store, err := NewEntityStore[Entry](newSpireTable(t), mockClient)
+    assert.NoError(t, err)
+
+    entry := Entry{SpiffeID: "spiffe://example.org/workload", ParentID: "spiffe://example.org/node"}
+    _, err = store.Put(ctx, entry)
+    assert.NoError(t, err)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.PutItemInput)
+    assert.Equal(t, "SpireTable", *input.TableName)
+    assert.Equal(t, &types.AttributeValueMemberS{Value: "PARENT#spiffe://example.org/node"}, input.Item["PK"])
+    assert.Equal(t, &types.AttributeValueMemberS{Value: "ENTRY#spiffe://example.org/workload"}, input.Item["SK"])
+    assert.Equal(t, &types.AttributeValueMemberS{Value: "Entry"}, input.Item["EntityType"])
+
+    key, err := store.KeyOf(entry)
+    assert.NoError(t, err)
+    assert.Equal(t, Key{Partition: input.Item["PK"], Sort: input.Item["SK"]}, key)
+
+    _, err = store.Update(ctx, key, Change{Name: "SpiffeID", Op: SetValue, Value: "spiffe://example.org/other"})
+    assert.Error(t, err)
+}
+
+func TestEntityStoreChecksEntityType(t *testing.T) {
+    ctx := context.Background()
+    entry := map[string]types.AttributeValue{
+        "PK":         &types.AttributeValueMemberS{Value: "BUNDLE#example.org"},
+        "SK":         &types.AttributeValueMemberS{Value: "META"},
+        "EntityType": &types.AttributeValueMemberS{Value: "Entry"},
+    }
+    conditionFailed := &types.ConditionalCheckFailedException{Item: entry}
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("UpdateItem", ctx, mock.Anything).Return((*dynamodb.UpdateItemOutput)(nil), conditionFailed)
+    mockClient.On("DeleteItem", ctx, mock.Anything).Return((*dynamodb.DeleteItemOutput)(nil), conditionFailed)
+
+    store, err := NewEntityStore[Bundle](newSpireTable(t), mockClient)
+    if !assert.NoError(t, err) {
+        return
+    }
+    key := Key{Partition: entry["PK"], Sort: entry["SK"]}
+
+    // The item under the key is an Entry, so to the Bundle store it is missing
+    _, err = store.Update(ctx, key, Change{Name: "Name", Op: SetValue, Value: "other"})
+    assert.ErrorIs(t, err, ErrNotFound)
+    update := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.UpdateItemInput)
+    assert.Contains(t, *update.ConditionExpression, "attribute_exists")
+    assert.Contains(t, updateNames(update), "EntityType")
+    assert.Contains(t, valuesOf(update.ExpressionAttributeValues), &types.AttributeValueMemberS{Value: "Bundle"})
+
+    assert.NoError(t, store.Delete(ctx, key))
+    remove := mockClient.Calls[1].Arguments.Get(1).(*dynamodb.DeleteItemInput)
+    if assert.NotNil(t, remove.ConditionExpression) {
+        assert.Contains(t, *remove.ConditionExpression, "attribute_not_exists")
+    }
+    var names []string
+    for _, name := range remove.ExpressionAttributeNames {
+        names = append(names, name)
+    }
+    assert.ElementsMatch(t, []string{"PK", "EntityType"}, names)
+    assert.Equal(t, []types.AttributeValue{&types.AttributeValueMemberS{Value: "Bundle"}}, valuesOf(remove.ExpressionAttributeValues))
+
+    op := store.TransactDelete(key)
+    if assert.NoError(t, op.err) && assert.NotNil(t, op.item.Delete.ConditionExpression) {
+        assert.ErrorIs(t, op.conditionFailed(entry), ErrNotFound)
+    }
+}
+
+func TestRegisterEntityRejectsInvalidEntities(t *testing.T) {
+    type keyed struct {
+        PK string
+        ID string
+    }
+
+    table := newSpireTable(t)
+    for _, err := range []error{
+        RegisterEntity[Bundle](table, Entity{Type: "Other", PartitionKey: "B#{ID}", SortKey: "META"}),
+        RegisterEntity[JoinToken](table, Entity{Type: "Bundle", PartitionKey: "T#{Token}", SortKey: "META"}),
+        RegisterEntity[JoinToken](table, Entity{Type: "JoinToken", PartitionKey: "T#{Missing}", SortKey: "META"}),
+        RegisterEntity[JoinToken](table, Entity{Type: "JoinToken", PartitionKey: "T#{Token", SortKey: "META"}),
+        RegisterEntity[JoinToken](table, Entity{Type: "JoinToken", PartitionKey: "T#{Token}", SortKey: ""}),
+        RegisterEntity[keyed](table, Entity{Type: "Keyed", PartitionKey: "K#{ID}", SortKey: "META"}),
+    } {
+        assert.Error(t, err)
+    }
+}
+
+func TestKeyTemplate(t *testing.T) {
+    template, err := parseKeyTemplate("BUNDLE#{TrustDomain}#{ID}")
+    assert.NoError(t, err)
+    assert.Equal(t, []string{"TrustDomain", "ID"}, template.fields())
+    assert.Equal(t, "BUNDLE#", template.prefix())
+
+    rendered, ok := template.render(map[string]types.AttributeValue{
+        "TrustDomain": &types.AttributeValueMemberS{Value: "example.org"},
+        "ID":          &types.AttributeValueMemberN{Value: "7"},
+    })
+    assert.True(t, ok)
+    assert.Equal(t, "BUNDLE#example.org#7", rendered)
+
+    _, ok = template.render(map[string]types.AttributeValue{"TrustDomain": &types.AttributeValueMemberS{Value: "example.org"}})
+    assert.False(t, ok)
+
+    for _, source := range []string{"A}", "{}", "A#{B{C}}", "A#{B"} {
+        _, err := parseKeyTemplate(source)
+        assert.Error(t, err, source)
+    }
+}
@@ -0,0 +1,451 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "reflect"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// dynamoStoreClient adds the item reads and writes used by Store to
+// dynamoQueryClient.
+type dynamoStoreClient interface {
+    dynamoQueryClient
+    GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+    PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+    DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+    UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+    BatchGetItem(ctx context.Context, input *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+    BatchWriteItem(ctx context.Context, input *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+// ErrNotFound is returned by Get and Update when no item has the key.
+var ErrNotFound = errors.New("item not found")
+
+// Key identifies one item by its partition key value and, on tables with a
+// sort key, its sort key value. Values are marshalled with attributevalue
+// unless they already are a types.AttributeValue.
+type Key struct {
+    Partition interface{}
+    Sort      interface{}
+}
+
+// UpdateOp is the action a Change applies to an attribute.
+type UpdateOp int
+
+const (
+    SetValue    UpdateOp = iota + 1
+    RemoveValue          // Value is ignored
+    AddValue             // adds to a number or set attribute
+    DeleteValue          // removes elements from a set attribute
+)
+
+// Change is one attribute change applied by Store.Update.
+type Change struct {
+    Name  string
+    Op    UpdateOp
+    Value interface{}
+}
+
+// Store reads and writes items of type T in the kind table, marshalled with
+// attributevalue like the items ListItems returns. partitionKey and sortKey
+// name the table keys as in ListItems; sortKey is empty for tables without
+// one. When T has a field tagged `dynamodbstore:"version"` every write is
+// checked and increments it, see versionField.
+type Store[T any] struct {
+    kind         string
+    client       dynamoStoreClient
+    partitionKey string
+    sortKey      string
+    version      *versionField
+    ttl          *ttlField
+    times        timeCodec
+
+    // Set for the entities of a SingleTable.
+    table  *SingleTable
+    entity *entity
+
+    backoff func(attempt int) time.Duration
+}
+
+func NewStore[T any](kind string, client dynamoStoreClient, partitionKey, sortKey string) (*Store[T], error) {
+    itemType := reflect.TypeOf((*T)(nil)).Elem()
+    version, err := findVersionField(itemType)
+    if err != nil {
+        return nil, err
+    }
+    ttl, err := findTTLField(itemType)
+    if err != nil {
+        return nil, err
+    }
+    times, err := timeCodecOf(itemType)
+    if err != nil {
+        return nil, err
+    }
+    return &Store[T]{kind: kind, client: client, partitionKey: partitionKey, sortKey: sortKey, version: version, ttl: ttl, times: times}, nil
+}
+
+// List runs ListItems against the store's table, or ListEntities for an
+// entity store.
+func (s *Store[T]) List(ctx context.Context, filters []Filter, pagination *Pagination, projection []string, opts ...Option) ([]T, *Pagination, error) {
+    if s.table != nil {
+        return ListEntities[T](ctx, s.table, s.client, filters, pagination, projection, opts...)
+    }
+    return ListItems[T](ctx, s.kind, s.client, s.partitionKey, s.sortKey, filters, pagination, projection, opts...)
+}
+
+// Put creates item or replaces the item with the same key and returns item
+// as stored. On a versioned store the stored item must still be at the
+// version item carries, 0 to create it, and the returned item holds the next
+// version. On error item is returned unchanged.
+func (s *Store[T]) Put(ctx context.Context, item T) (T, error) {
+    stored := item
+    expected := s.bumpVersion(&stored)
+    record, err := s.marshalItem(stored)
+    if err != nil {
+        return item, err
+    }
+    input := &dynamodb.PutItemInput{
+        TableName: aws.String(s.kind),
+        Item:      record,
+    }
+
+    if s.version != nil {
+        expr, err := expression.NewBuilder().WithCondition(s.version.condition(expected)).Build()
+        if err != nil {
+            return item, fmt.Errorf("error to building expression: %w", err)
+        }
+        input.ConditionExpression = expr.Condition()
+        input.ExpressionAttributeNames = expr.Names()
+        input.ExpressionAttributeValues = expr.Values()
+        input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+    }
+
+    _, err = s.client.PutItem(ctx, input)
+    var conditionFailed *types.ConditionalCheckFailedException
+    if errors.As(err, &conditionFailed) {
+        return item, s.version.conflict(expected, conditionFailed.Item)
+    }
+    if err != nil {
+        return item, fmt.Errorf("failed to put record: %w", err)
+    }
+    return stored, nil
+}
+
+// Get reads the item with key using a strongly consistent read.
+func (s *Store[T]) Get(ctx context.Context, key Key) (T, error) {
+    var item T
+    dynamoKey, err := s.key(key)
+    if err != nil {
+        return item, err
+    }
+
+    output, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+        TableName:      aws.String(s.kind),
+        Key:            dynamoKey,
+        ConsistentRead: aws.Bool(true),
+    })
+    if err != nil {
+        return item, fmt.Errorf("failed to get record: %w", err)
+    }
+    if output.Item == nil {
+        return item, ErrNotFound
+    }
+
+    if err := s.times.unmarshalRecord(output.Item, &item); err != nil {
+        return item, fmt.Errorf("failed to decode record: %w", err)
+    }
+    return item, nil
+}
+
+// Delete removes the item with key. Deleting a missing item is not an error.
+func (s *Store[T]) Delete(ctx context.Context, key Key) error {
+    return s.delete(ctx, key, nil)
+}
+
+// DeleteIfVersion removes the item with key only while it is at version.
+func (s *Store[T]) DeleteIfVersion(ctx context.Context, key Key, version int64) error {
+    if err := s.requireVersion(); err != nil {
+        return err
+    }
+    return s.delete(ctx, key, &version)
+}
+
+func (s *Store[T]) delete(ctx context.Context, key Key, version *int64) error {
+    dynamoKey, err := s.key(key)
+    if err != nil {
+        return err
+    }
+    input := &dynamodb.DeleteItemInput{
+        TableName: aws.String(s.kind),
+        Key:       dynamoKey,
+    }
+
+    if condition, ok := s.deleteCondition(version); ok {
+        expr, err := expression.NewBuilder().WithCondition(condition).Build()
+        if err != nil {
+            return fmt.Errorf("error to building expression: %w", err)
+        }
+        input.ConditionExpression = expr.Condition()
+        input.ExpressionAttributeNames = expr.Names()
+        input.ExpressionAttributeValues = expr.Values()
+        input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+    }
+
+    _, err = s.client.DeleteItem(ctx, input)
+    var conditionFailed *types.ConditionalCheckFailedException
+    if errors.As(err, &conditionFailed) {
+        return s.deleteFailed(version, conditionFailed.Item)
+    }
+    if err != nil {
+        return fmt.Errorf("failed to delete record: %w", err)
+    }
+    return nil
+}
+
+// deleteCondition is the condition of a delete: on an entity store a stored
+// item must be of the entity, and with version it must be at that version.
+// ok is false when the delete is unconditional.
+func (s *Store[T]) deleteCondition(version *int64) (condition expression.ConditionBuilder, ok bool) {
+    if s.entity != nil {
+        // A missing item is still deleted without error
+        missing := expression.AttributeNotExists(expression.Name(s.partitionKey))
+        condition, ok = expression.Or(missing, s.typeCondition()), true
+    }
+    if version != nil {
+        if ok {
+            condition = condition.And(s.version.condition(*version))
+        } else {
+            condition, ok = s.version.condition(*version), true
+        }
+    }
+    return condition, ok
+}
+
+// deleteFailed is the error of a delete whose condition failed on current.
+// An item of another entity counts as missing.
+func (s *Store[T]) deleteFailed(version *int64, current map[string]types.AttributeValue) error {
+    if s.foreign(current) {
+        current = nil
+    }
+    if version == nil {
+        return nil
+    }
+    return s.version.conflict(*version, current)
+}
+
+// Update applies changes to the existing item with key and returns the item
+// as stored afterwards. Unlike a plain UpdateItem it never creates an item.
+// On a versioned store it also increments the version.
+func (s *Store[T]) Update(ctx context.Context, key Key, changes ...Change) (T, error) {
+    return s.update(ctx, key, nil, changes)
+}
+
+// UpdateIfVersion is Update applied only while the item is at version.
+func (s *Store[T]) UpdateIfVersion(ctx context.Context, key Key, version int64, changes ...Change) (T, error) {
+    if err := s.requireVersion(); err != nil {
+        var item T
+        return item, err
+    }
+    return s.update(ctx, key, &version, changes)
+}
+
+func (s *Store[T]) requireVersion() error {
+    if s.version == nil {
+        var item T
+        return fmt.Errorf("%T has no field tagged %s:\"version\"", item, tagName)
+    }
+    return nil
+}
+
+func (s *Store[T]) update(ctx context.Context, key Key, version *int64, changes []Change) (T, error) {
+    var item T
+    dynamoKey, err := s.key(key)
+    if err != nil {
+        return item, err
+    }
+
+    expr, err := s.updateExpression(version, changes)
+    if err != nil {
+        return item, err
+    }
+
+    output, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+        TableName:                           aws.String(s.kind),
+        Key:                                 dynamoKey,
+        UpdateExpression:                    expr.Update(),
+        ConditionExpression:                 expr.Condition(),
+        ExpressionAttributeNames:            expr.Names(),
+        ExpressionAttributeValues:           expr.Values(),
+        ReturnValues:                        types.ReturnValueAllNew,
+        ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+    })
+    var conditionFailed *types.ConditionalCheckFailedException
+    if errors.As(err, &conditionFailed) {
+        if conditionFailed.Item == nil || version == nil || s.foreign(conditionFailed.Item) {
+            return item, ErrNotFound
+        }
+        return item, s.version.conflict(*version, conditionFailed.Item)
+    }
+    if err != nil {
+        return item, fmt.Errorf("failed to update record: %w", err)
+    }
+
+    if err := s.times.unmarshalRecord(output.Attributes, &item); err != nil {
+        return item, fmt.Errorf("failed to decode record: %w", err)
+    }
+    return item, nil
+}
+
+// bumpVersion increments the version of item on a versioned store and
+// returns the version the stored item must have for the write to apply.
+func (s *Store[T]) bumpVersion(item *T) int64 {
+    if s.version == nil {
+        return 0
+    }
+    value := reflect.ValueOf(item).Elem()
+    expected := s.version.get(value)
+    s.version.set(value, expected+1)
+    return expected
+}
+
+// updateExpression builds the update applying changes, conditioned on the
+// item existing, being of the entity on an entity store and, when version
+// is set, being at that version.
+func (s *Store[T]) updateExpression(version *int64, changes []Change) (expression.Expression, error) {
+    update, err := s.updateBuilder(changes)
+    if err != nil {
+        return expression.Expression{}, err
+    }
+    condition := expression.AttributeExists(expression.Name(s.partitionKey))
+    if s.entity != nil {
+        condition = condition.And(s.typeCondition())
+    }
+    if version != nil {
+        condition = condition.And(s.version.condition(*version))
+    }
+    expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+    if err != nil {
+        return expression.Expression{}, fmt.Errorf("error to building expression: %w", err)
+    }
+    return expr, nil
+}
+
+// key marshals key into the table's key attributes.
+func (s *Store[T]) key(key Key) (map[string]types.AttributeValue, error) {
+    if key.Partition == nil {
+        return nil, fmt.Errorf("missing value for partition key %q", s.partitionKey)
+    }
+    if s.sortKey == "" && key.Sort != nil {
+        return nil, fmt.Errorf("table %q has no sort key", s.kind)
+    }
+    if s.sortKey != "" && key.Sort == nil {
+        return nil, fmt.Errorf("missing value for sort key %q", s.sortKey)
+    }
+
+    partition, err := keyValue(s.times.encode(s.partitionKey, key.Partition))
+    if err != nil {
+        return nil, fmt.Errorf("invalid partition key: %w", err)
+    }
+    dynamoKey := map[string]types.AttributeValue{s.partitionKey: partition}
+
+    if s.sortKey != "" {
+        sort, err := keyValue(s.times.encode(s.sortKey, key.Sort))
+        if err != nil {
+            return nil, fmt.Errorf("invalid sort key: %w", err)
+        }
+        dynamoKey[s.sortKey] = sort
+    }
+    return dynamoKey, nil
+}
+
+// keyValue marshals a Key value, which may already be an attribute value.
+func keyValue(value interface{}) (types.AttributeValue, error) {
+    if av, ok := value.(types.AttributeValue); ok {
+        return av, nil
+    }
+    return attributevalue.Marshal(value)
+}
+
+// marshalItem encodes item and checks that it carries the table keys.
+func (s *Store[T]) marshalItem(item T) (map[string]types.AttributeValue, error) {
+    record, err := attributevalue.MarshalMap(item)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode record: %w", err)
+    }
+    if err := s.times.encodeRecord(record); err != nil {
+        return nil, err
+    }
+    if s.ttl != nil {
+        s.ttl.addEpoch(record, reflect.ValueOf(item))
+    }
+    if s.entity != nil {
+        if err := s.entityAttributes(record); err != nil {
+            return nil, err
+        }
+    }
+    for _, name := range []string{s.partitionKey, s.sortKey} {
+        if _, ok := record[name]; name != "" && !ok {
+            return nil, fmt.Errorf("record has no value for key attribute %q", name)
+        }
+    }
+    return record, nil
+}
+
+func (s *Store[T]) updateBuilder(changes []Change) (expression.UpdateBuilder, error) {
+    var update expression.UpdateBuilder
+    if len(changes) == 0 {
+        return update, errors.New("update without changes")
+    }
+
+    for _, change := range changes {
+        if change.Name == "" {
+            return update, errors.New("change without an attribute name")
+        }
+        if change.Name == s.partitionKey || change.Name == s.sortKey {
+            return update, fmt.Errorf("cannot update key attribute %q", change.Name)
+        }
+        if s.version != nil && change.Name == s.version.attribute {
+            return update, fmt.Errorf("cannot update version attribute %q", change.Name)
+        }
+        if s.entity != nil && s.entity.managed(s.table, change.Name) {
+            return update, fmt.Errorf("cannot update %q, it makes up the key or type of %s", change.Name, s.entity.name)
+        }
+        if s.ttl != nil && change.Name == s.ttl.epoch {
+            return update, fmt.Errorf("cannot update ttl attribute %q, set %q instead", change.Name, s.ttl.attribute)
+        }
+
+        field := expression.Name(change.Name)
+        switch change.Op {
+        case SetValue:
+            update = update.Set(field, expression.Value(s.times.encode(change.Name, change.Value)))
+        case RemoveValue:
+            update = update.Remove(field)
+        case AddValue:
+            update = update.Add(field, expression.Value(change.Value))
+        case DeleteValue:
+            update = update.Delete(field, expression.Value(change.Value))
+        default:
+            return update, fmt.Errorf("unknown update operation for %q: %d", change.Name, change.Op)
+        }
+
+        if s.ttl != nil && change.Name == s.ttl.attribute {
+            var err error
+            if update, err = s.ttl.update(update, change); err != nil {
+                return update, err
+            }
+        }
+    }
+
+    if s.version != nil {
+        update = update.Add(expression.Name(s.version.attribute), expression.Value(1))
+    }
+    return update, nil
+}
@@ -0,0 +1,117 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestStorePutAndGet(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("PutItem", ctx, mock.Anything).Return(&dynamodb.PutItemOutput{}, nil).Once()
+    mockClient.On("GetItem", ctx, mock.Anything).Return(&dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+        "ID":   &types.AttributeValueMemberS{Value: "bundle-1"},
+        "Name": &types.AttributeValueMemberS{Value: "example.org"},
+    }}, nil).Once()
+
+    store, err := NewStore[Bundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+    _, err = store.Put(ctx, Bundle{ID: "bundle-1", Name: "example.org"})
+    assert.NoError(t, err)
+
+    bundle, err := store.Get(ctx, Key{Partition: "bundle-1"})
+    assert.NoError(t, err)
+    assert.Equal(t, Bundle{ID: "bundle-1", Name: "example.org"}, bundle)
+
+    put := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.PutItemInput)
+    assert.Equal(t, "BundlesTable", *put.TableName)
+    assert.Equal(t, &types.AttributeValueMemberS{Value: "bundle-1"}, put.Item["ID"])
+
+    get := mockClient.Calls[1].Arguments.Get(1).(*dynamodb.GetItemInput)
+    assert.Equal(t, map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "bundle-1"}}, get.Key)
+    assert.True(t, *get.ConsistentRead)
+}
+
+func TestStoreGetMissingItem(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("GetItem", ctx, mock.Anything).Return(&dynamodb.GetItemOutput{}, nil)
+
+    store, err := NewStore[Bundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+    _, err = store.Get(ctx, Key{Partition: "bundle-1"})
+    assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStoreDeleteWithSortKey(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("DeleteItem", ctx, mock.Anything).Return(&dynamodb.DeleteItemOutput{}, nil)
+
+    store, err := NewStore[EntryEvent]("EntryEventsTable", mockClient, "EventID", "CreatedAt")
+    assert.NoError(t, err)
+    assert.NoError(t, store.Delete(ctx, Key{Partition: 7, Sort: "2024-01-01T00:00:00Z"}))
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.DeleteItemInput)
+    assert.Equal(t, map[string]types.AttributeValue{
+        "EventID":   &types.AttributeValueMemberN{Value: "7"},
+        "CreatedAt": &types.AttributeValueMemberS{Value: "2024-01-01T00:00:00Z"},
+    }, input.Key)
+}
+
+func TestStoreUpdate(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("UpdateItem", ctx, mock.Anything).Return(&dynamodb.UpdateItemOutput{Attributes: map[string]types.AttributeValue{
+        "ID":   &types.AttributeValueMemberS{Value: "bundle-1"},
+        "Name": &types.AttributeValueMemberS{Value: "renamed.org"},
+    }}, nil).Once()
+
+    store, err := NewStore[Bundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+    bundle, err := store.Update(ctx, Key{Partition: "bundle-1"}, Change{Name: "Name", Op: SetValue, Value: "renamed.org"})
+    assert.NoError(t, err)
+    assert.Equal(t, "renamed.org", bundle.Name)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.UpdateItemInput)
+    assert.Contains(t, *input.UpdateExpression, "SET")
+    assert.Contains(t, *input.ConditionExpression, "attribute_exists")
+    assert.Equal(t, types.ReturnValueAllNew, input.ReturnValues)
+
+    // The condition only fails when the item does not exist
+    mockClient.On("UpdateItem", ctx, mock.Anything).Return((*dynamodb.UpdateItemOutput)(nil), &types.ConditionalCheckFailedException{}).Once()
+    _, err = store.Update(ctx, Key{Partition: "bundle-2"}, Change{Name: "Name", Op: RemoveValue})
+    assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStoreRejectsInvalidRequests(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    store, err := NewStore[EntryEvent]("EntryEventsTable", mockClient, "EventID", "CreatedAt")
+    assert.NoError(t, err)
+
+    _, err = store.Get(ctx, Key{Partition: 7})
+    assert.Error(t, err)
+
+    _, err = store.Update(ctx, Key{Partition: 7, Sort: "2024-01-01T00:00:00Z"})
+    assert.Error(t, err)
+
+    _, err = store.Update(ctx, Key{Partition: 7, Sort: "2024-01-01T00:00:00Z"}, Change{Name: "EventID", Op: SetValue, Value: 8})
+    assert.Error(t, err)
+
+    bundles, err := NewStore[Bundle]("BundlesTable", mockClient, "BundleID", "")
+    assert.NoError(t, err)
+    _, err = bundles.Put(ctx, Bundle{ID: "bundle-1"})
+    assert.Error(t, err)
+    assert.Error(t, bundles.Delete(ctx, Key{Partition: "bundle-1", Sort: "x"}))
+
+    mockClient.AssertNotCalled(t, "GetItem", mock.Anything, mock.Anything)
+    mockClient.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.Anything)
+    mockClient.AssertNotCalled(t, "PutItem", mock.Anything, mock.Anything)
+    mockClient.AssertNotCalled(t, "DeleteItem", mock.Anything, mock.Anything)
+}
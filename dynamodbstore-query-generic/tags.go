@@ -0,0 +1,95 @@
+package dynamodbstore
+
+import (
+    "reflect"
+    "strings"
+)
+
+// tagName is the struct tag holding the options this package reads from a
+// field, e.g. `dynamodbstore:"version"`.
+const tagName = "dynamodbstore"
+
+// taggedField is a field of an item type with the attribute it is stored in
+// and its dynamodbstore options.
+type taggedField struct {
+    name      string
+    index     []int
+    kind      reflect.Kind
+    attribute string
+    options   []string
+}
+
+func (f taggedField) hasOption(option string) bool {
+    for _, o := range f.options {
+        if o == option {
+            return true
+        }
+    }
+    return false
+}
+
+// option looks up an option that may carry a value, as in "ttl=Expiry".
+func (f taggedField) option(name string) (value string, ok bool) {
+    for _, o := range f.options {
+        key, value, _ := strings.Cut(o, "=")
+        if key == name {
+            return value, true
+        }
+    }
+    return "", false
+}
+
+// taggedFields lists the fields of itemType carrying a dynamodbstore tag,
+// named as attributevalue names their attributes.
+func taggedFields(itemType reflect.Type) []taggedField {
+    if itemType.Kind() != reflect.Struct {
+        return nil
+    }
+
+    var fields []taggedField
+    for _, field := range reflect.VisibleFields(itemType) {
+        tag, ok := field.Tag.Lookup(tagName)
+        attribute, stored := attributeName(field)
+        if !ok || !stored {
+            continue
+        }
+        fields = append(fields, taggedField{
+            name:      field.Name,
+            index:     field.Index,
+            kind:      field.Type.Kind(),
+            attribute: attribute,
+            options:   strings.Split(tag, ","),
+        })
+    }
+    return fields
+}
+
+// itemAttributes lists the attributes attributevalue stores for itemType.
+func itemAttributes(itemType reflect.Type) map[string]bool {
+    attributes := make(map[string]bool)
+    if itemType.Kind() != reflect.Struct {
+        return attributes
+    }
+    for _, field := range reflect.VisibleFields(itemType) {
+        if name, ok := attributeName(field); ok {
+            attributes[name] = true
+        }
+    }
+    return attributes
+}
+
+// attributeName is the attribute attributevalue stores field in; ok is false
+// for fields it skips and for embedded structs, whose fields are promoted.
+func attributeName(field reflect.StructField) (name string, ok bool) {
+    if !field.IsExported() || field.Anonymous {
+        return "", false
+    }
+    name, _, _ = strings.Cut(field.Tag.Get("dynamodbav"), ",")
+    switch name {
+    case "-":
+        return "", false
+    case "":
+        return field.Name, true
+    }
+    return name, true
+}
@@ -0,0 +1,203 @@
+package dynamodbstore
+
+import (
+    "fmt"
+    "reflect"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Time encodings selected with `dynamodbstore:"time=<encoding>"` on a
+// time.Time field. Fields without the tag keep attributevalue's encoding.
+const (
+    timeRFC3339Nano = "rfc3339nano" // S, nanosecond precision in UTC
+    timeUnix        = "unix"        // N, seconds since the epoch
+    timeUnixMilli   = "unixmilli"   // N, milliseconds since the epoch
+)
+
+// sortableRFC3339Nano always writes nine fractional digits, unlike
+// time.RFC3339Nano which trims trailing zeros, so encoded strings sort in
+// time order. Parsing with time.RFC3339Nano accepts both forms.
+const sortableRFC3339Nano = "2006-01-02T15:04:05.000000000Z07:00"
+
+// timeCodec maps the attributes of an item type to their time encoding.
+// Items are encoded after attributevalue marshals them and decoded before
+// it unmarshals them, and filter, key and update values of those attributes
+// are encoded the same way so DynamoDB compares like with like.
+type timeCodec map[string]string
+
+var timeCodecs sync.Map // reflect.Type to timeCodec
+
+func timeCodecOf(itemType reflect.Type) (timeCodec, error) {
+    if cached, ok := timeCodecs.Load(itemType); ok {
+        return cached.(timeCodec), nil
+    }
+
+    codec := make(timeCodec)
+    for _, field := range taggedFields(itemType) {
+        encoding, ok := field.option("time")
+        if !ok {
+            continue
+        }
+        switch encoding {
+        case timeRFC3339Nano, timeUnix, timeUnixMilli:
+        default:
+            return nil, fmt.Errorf("unknown time encoding %q for field %s of %v", encoding, field.name, itemType)
+        }
+        if fieldType := itemType.FieldByIndex(field.index).Type; fieldType != timeType && fieldType != reflect.PointerTo(timeType) {
+            return nil, fmt.Errorf("time field %s of %v must be a time.Time, not %v", field.name, itemType, fieldType)
+        }
+        codec[field.attribute] = encoding
+    }
+    timeCodecs.Store(itemType, codec)
+    return codec, nil
+}
+
+func timeCodecFor[T any]() (timeCodec, error) {
+    return timeCodecOf(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// scalarType is the key type of attribute, if the codec encodes it.
+func (c timeCodec) scalarType(attribute string) (types.ScalarAttributeType, bool) {
+    switch c[attribute] {
+    case "":
+        return "", false
+    case timeRFC3339Nano:
+        return types.ScalarAttributeTypeS, true
+    }
+    return types.ScalarAttributeTypeN, true
+}
+
+// encode converts value to the encoding of attribute when it is a time and
+// returns it unchanged otherwise.
+func (c timeCodec) encode(attribute string, value interface{}) interface{} {
+    encoding, ok := c[attribute]
+    if !ok {
+        return value
+    }
+
+    var t time.Time
+    switch v := value.(type) {
+    case time.Time:
+        t = v
+    case *time.Time:
+        if v == nil {
+            return value
+        }
+        t = *v
+    default:
+        return value
+    }
+
+    switch encoding {
+    case timeUnix:
+        return t.Unix()
+    case timeUnixMilli:
+        return t.UnixMilli()
+    }
+    return t.UTC().Format(sortableRFC3339Nano)
+}
+
+// encodeFilters returns filters with their time values encoded.
+func (c timeCodec) encodeFilters(filters []Filter) []Filter {
+    if len(c) == 0 || len(filters) == 0 {
+        return filters
+    }
+
+    encoded := make([]Filter, len(filters))
+    for i, filter := range filters {
+        if filter.Combinator != 0 {
+            filter.Filters = c.encodeFilters(filter.Filters)
+        } else if _, ok := c[filter.Name]; ok {
+            filter.Value = c.encodeFilterValue(filter)
+        }
+        encoded[i] = filter
+    }
+    return encoded
+}
+
+func (c timeCodec) encodeFilterValue(filter Filter) interface{} {
+    switch filter.Op {
+    case Between:
+        if bounds, ok := filter.Value.(Range); ok {
+            return Range{Lower: c.encode(filter.Name, bounds.Lower), Upper: c.encode(filter.Name, bounds.Upper)}
+        }
+    case In:
+        values, err := setValues(filter)
+        if err != nil {
+            return filter.Value
+        }
+        for i, value := range values {
+            values[i] = c.encode(filter.Name, value)
+        }
+        return values
+    }
+    return c.encode(filter.Name, filter.Value)
+}
+
+// encodeRecord re-encodes the time attributes of a marshalled record,
+// which attributevalue stores as RFC 3339 strings.
+func (c timeCodec) encodeRecord(record map[string]types.AttributeValue) error {
+    for attribute := range c {
+        value, ok := record[attribute].(*types.AttributeValueMemberS)
+        if !ok {
+            continue
+        }
+        t, err := time.Parse(time.RFC3339Nano, value.Value)
+        if err != nil {
+            return fmt.Errorf("invalid time in %q: %w", attribute, err)
+        }
+        if record[attribute], err = attributevalue.Marshal(c.encode(attribute, t)); err != nil {
+            return fmt.Errorf("failed to encode %q: %w", attribute, err)
+        }
+    }
+    return nil
+}
+
+// decodeRecord turns the numeric time attributes of record back into the
+// strings attributevalue decodes.
+func (c timeCodec) decodeRecord(record map[string]types.AttributeValue) error {
+    for attribute, encoding := range c {
+        value, ok := record[attribute].(*types.AttributeValueMemberN)
+        if !ok {
+            continue
+        }
+        n, err := strconv.ParseInt(value.Value, 10, 64)
+        if err != nil {
+            return fmt.Errorf("invalid time in %q: %w", attribute, err)
+        }
+
+        t := time.Unix(n, 0)
+        if encoding == timeUnixMilli {
+            t = time.UnixMilli(n)
+        }
+        record[attribute] = &types.AttributeValueMemberS{Value: t.UTC().Format(time.RFC3339Nano)}
+    }
+    return nil
+}
+
+// unmarshalRecord decodes record into item.
+func (c timeCodec) unmarshalRecord(record map[string]types.AttributeValue, item interface{}) error {
+    if err := c.decodeRecord(record); err != nil {
+        return err
+    }
+    return attributevalue.UnmarshalMap(record, item)
+}
+
+// unmarshalRecords decodes records into a slice of T.
+func unmarshalRecords[T any](c timeCodec, records []map[string]types.AttributeValue) ([]T, error) {
+    for _, record := range records {
+        if err := c.decodeRecord(record); err != nil {
+            return nil, err
+        }
+    }
+    var items []T
+    if err := attributevalue.UnmarshalListOfMaps(records, &items); err != nil {
+        return nil, err
+    }
+    return items, nil
+}
@@ -0,0 +1,168 @@
+package dynamodbstore
+
+import (
+    "context"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+// NodeEvent sorted by a numeric timestamp
+type TimedNodeEvent struct {
+    NodeID    string
+    Timestamp time.Time `dynamodbstore:"time=unixmilli"`
+}
+
+func TestTimeCodecOf(t *testing.T) {
+    codec, err := timeCodecOf(reflect.TypeOf(struct {
+        CreatedAt time.Time  `dynamodbstore:"time=unix"`
+        UpdatedAt *time.Time `dynamodbstore:"time=rfc3339nano" dynamodbav:"updated"`
+        DeletedAt time.Time
+    }{}))
+    assert.NoError(t, err)
+    assert.Equal(t, timeCodec{"CreatedAt": timeUnix, "updated": timeRFC3339Nano}, codec)
+
+    for _, itemType := range []reflect.Type{
+        reflect.TypeOf(struct {
+            CreatedAt time.Time `dynamodbstore:"time=iso"`
+        }{}),
+        reflect.TypeOf(struct {
+            CreatedAt int64 `dynamodbstore:"time=unix"`
+        }{}),
+    } {
+        _, err := timeCodecOf(itemType)
+        assert.Error(t, err, "%v", itemType)
+    }
+
+    at := time.Date(2024, 1, 1, 12, 0, 0, 500, time.FixedZone("BRT", -3*60*60))
+    assert.Equal(t, int64(1704121200), timeCodec{"T": timeUnix}.encode("T", at))
+    assert.Equal(t, int64(1704121200000), timeCodec{"T": timeUnixMilli}.encode("T", &at))
+    assert.Equal(t, "2024-01-01T15:00:00.000000500Z", timeCodec{"T": timeRFC3339Nano}.encode("T", at))
+    assert.Equal(t, "2024-01-01", timeCodec{"T": timeUnix}.encode("T", "2024-01-01"))
+}
+
+func TestRFC3339NanoSortsInTimeOrder(t *testing.T) {
+    codec := timeCodec{"T": timeRFC3339Nano}
+    at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+    times := []time.Time{at, at.Add(100 * time.Millisecond), at.Add(120 * time.Millisecond), at.Add(time.Second), at.Add(time.Second + time.Nanosecond)}
+    for i := 1; i < len(times); i++ {
+        earlier, later := codec.encode("T", times[i-1]).(string), codec.encode("T", times[i]).(string)
+        assert.Less(t, earlier, later)
+    }
+
+    // Strings written before the fixed width layout still decode
+    record := map[string]types.AttributeValue{"T": &types.AttributeValueMemberS{Value: "2024-01-01T12:00:00.1Z"}}
+    assert.NoError(t, codec.encodeRecord(record))
+    assert.Equal(t, &types.AttributeValueMemberS{Value: "2024-01-01T12:00:00.100000000Z"}, record["T"])
+    var item struct{ T time.Time }
+    assert.NoError(t, codec.unmarshalRecord(map[string]types.AttributeValue{"T": &types.AttributeValueMemberS{Value: "2024-01-01T12:00:00.1Z"}}, &item))
+    assert.True(t, at.Add(100*time.Millisecond).Equal(item.T), "got %v", item.T)
+}
+
+func TestStoreEncodesTimes(t *testing.T) {
+    ctx := context.Background()
+    at := time.UnixMilli(1704067200123).UTC()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("PutItem", ctx, mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+    mockClient.On("GetItem", ctx, mock.Anything).Return(&dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+        "NodeID":    &types.AttributeValueMemberS{Value: "node-1"},
+        "Timestamp": &types.AttributeValueMemberN{Value: "1704067200123"},
+    }}, nil)
+    mockClient.On("UpdateItem", ctx, mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil)
+
+    store, err := NewStore[TimedNodeEvent]("NodeEventsTable", mockClient, "NodeID", "Timestamp")
+    assert.NoError(t, err)
+    _, err = store.Put(ctx, TimedNodeEvent{NodeID: "node-1", Timestamp: at})
+    assert.NoError(t, err)
+    put := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.PutItemInput)
+    assert.Equal(t, &types.AttributeValueMemberN{Value: "1704067200123"}, put.Item["Timestamp"])
+
+    event, err := store.Get(ctx, Key{Partition: "node-1", Sort: at})
+    assert.NoError(t, err)
+    assert.True(t, at.Equal(event.Timestamp), "got %v", event.Timestamp)
+    get := mockClient.Calls[1].Arguments.Get(1).(*dynamodb.GetItemInput)
+    assert.Equal(t, &types.AttributeValueMemberN{Value: "1704067200123"}, get.Key["Timestamp"])
+
+    store, err = NewStore[TimedNodeEvent]("NodeEventsTable", mockClient, "NodeID", "")
+    assert.NoError(t, err)
+    _, err = store.Update(ctx, Key{Partition: "node-1"}, Change{Name: "Timestamp", Op: SetValue, Value: at})
+    assert.NoError(t, err)
+    update := mockClient.Calls[2].Arguments.Get(1).(*dynamodb.UpdateItemInput)
+    assert.Contains(t, valuesOf(update.ExpressionAttributeValues), types.AttributeValue(&types.AttributeValueMemberN{Value: "1704067200123"}))
+
+    _, err = NewStore[struct {
+        ID string `dynamodbstore:"time=unix"`
+    }]("Table", mockClient, "ID", "")
+    assert.Error(t, err)
+}
+
+func TestListItemsEncodesTimeFilters(t *testing.T) {
+    ctx := context.Background()
+    from := time.UnixMilli(1704067200000)
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{{
+        "NodeID":    &types.AttributeValueMemberS{Value: "node-1"},
+        "Timestamp": &types.AttributeValueMemberN{Value: "1704067260000"},
+    }}}, nil)
+
+    events, _, err := ListItems[TimedNodeEvent](ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", []Filter{
+        {Name: "NodeID", Op: EqualTo, Value: "node-1"},
+        {Name: "Timestamp", Op: Between, Value: Range{Lower: from, Upper: from.Add(time.Hour)}},
+    }, nil, nil)
+    assert.NoError(t, err)
+    assert.Len(t, events, 1)
+    assert.True(t, from.Add(time.Minute).Equal(events[0].Timestamp), "got %v", events[0].Timestamp)
+
+    // The sort key condition compares numbers
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Contains(t, *input.KeyConditionExpression, "BETWEEN")
+    assert.Subset(t, valuesOf(input.ExpressionAttributeValues), []types.AttributeValue{
+        &types.AttributeValueMemberN{Value: "1704067200000"},
+        &types.AttributeValueMemberN{Value: "1704070800000"},
+    })
+
+    // In candidates are encoded one by one
+    _, _, err = ListItems[TimedNodeEvent](ctx, "NodeEventsTable", mockClient, "NodeID", "", []Filter{
+        {Name: "NodeID", Op: EqualTo, Value: "node-1"},
+        {Name: "Timestamp", Op: In, Value: []time.Time{from, from.Add(time.Minute)}},
+    }, nil, nil)
+    assert.NoError(t, err)
+    input = mockClient.Calls[1].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Contains(t, valuesOf(input.ExpressionAttributeValues), types.AttributeValue(&types.AttributeValueMemberN{Value: "1704067260000"}))
+}
+
+func TestCountItemsOfEncodesTimeFilters(t *testing.T) {
+    ctx := context.Background()
+    from := time.UnixMilli(1704067200000)
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Count: 2, ScannedCount: 2}, nil)
+
+    count, err := CountItemsOf[TimedNodeEvent](ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", []Filter{
+        {Name: "NodeID", Op: EqualTo, Value: "node-1"},
+        {Name: "Timestamp", Op: GreaterOrEqual, Value: from},
+    })
+    assert.NoError(t, err)
+    assert.Equal(t, int64(2), count.Count)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Equal(t, types.SelectCount, input.Select)
+    assert.Contains(t, valuesOf(input.ExpressionAttributeValues), types.AttributeValue(&types.AttributeValueMemberN{Value: "1704067200000"}))
+}
+
+func TestRegisterTimeEncodedKey(t *testing.T) {
+    registry := NewRegistry()
+    assert.NoError(t, Register[TimedNodeEvent](registry, Schema{Table: "NodeEventsTable", PartitionKey: "NodeID", SortKey: "Timestamp"}))
+
+    definitions, err := registry.tableDefinitions()
+    assert.NoError(t, err)
+    assert.Contains(t, definitions[0].createTableInput().AttributeDefinitions, types.AttributeDefinition{
+        AttributeName: aws.String("Timestamp"),
+        AttributeType: types.ScalarAttributeTypeN,
+    })
+}
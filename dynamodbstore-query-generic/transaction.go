@@ -0,0 +1,314 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "fmt"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxTransactItems is the most operations DynamoDB accepts in one
+// transaction.
+const maxTransactItems = 100
+
+type dynamoTransactClient interface {
+    TransactWriteItems(ctx context.Context, input *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+    TransactGetItems(ctx context.Context, input *dynamodb.TransactGetItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error)
+}
+
+// ErrConditionFailed is the error of a TransactCheck whose filters did not
+// hold.
+var ErrConditionFailed = errors.New("condition check failed")
+
+// TransactOp is one operation of a WriteTransaction, built by the Transact
+// methods of the Store of its table.
+type TransactOp struct {
+    name string
+    item types.TransactWriteItem
+    err  error
+
+    // conditionFailed turns the item DynamoDB returns with a failed
+    // condition into the operation's error.
+    conditionFailed func(current map[string]types.AttributeValue) error
+}
+
+// TransactPut is Put as a transaction operation. On a versioned store the
+// item is stored with the version it carries plus one.
+func (s *Store[T]) TransactPut(item T) TransactOp {
+    op := TransactOp{name: "Put " + s.kind}
+    expected := s.bumpVersion(&item)
+    record, err := s.marshalItem(item)
+    if err != nil {
+        op.err = err
+        return op
+    }
+
+    put := &types.Put{TableName: aws.String(s.kind), Item: record}
+    if s.version != nil {
+        expr, err := expression.NewBuilder().WithCondition(s.version.condition(expected)).Build()
+        if err != nil {
+            op.err = fmt.Errorf("error to building expression: %w", err)
+            return op
+        }
+        put.ConditionExpression = expr.Condition()
+        put.ExpressionAttributeNames = expr.Names()
+        put.ExpressionAttributeValues = expr.Values()
+        put.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+        op.conditionFailed = func(current map[string]types.AttributeValue) error {
+            return s.version.conflict(expected, current)
+        }
+    }
+    op.item.Put = put
+    return op
+}
+
+// TransactUpdate is Update as a transaction operation.
+func (s *Store[T]) TransactUpdate(key Key, changes ...Change) TransactOp {
+    return s.transactUpdate(key, nil, changes)
+}
+
+// TransactUpdateIfVersion is UpdateIfVersion as a transaction operation.
+func (s *Store[T]) TransactUpdateIfVersion(key Key, version int64, changes ...Change) TransactOp {
+    if err := s.requireVersion(); err != nil {
+        return TransactOp{name: "Update " + s.kind, err: err}
+    }
+    return s.transactUpdate(key, &version, changes)
+}
+
+func (s *Store[T]) transactUpdate(key Key, version *int64, changes []Change) TransactOp {
+    op := TransactOp{name: "Update " + s.kind}
+    dynamoKey, err := s.key(key)
+    if err != nil {
+        op.err = err
+        return op
+    }
+    expr, err := s.updateExpression(version, changes)
+    if err != nil {
+        op.err = err
+        return op
+    }
+
+    op.item.Update = &types.Update{
+        TableName:                           aws.String(s.kind),
+        Key:                                 dynamoKey,
+        UpdateExpression:                    expr.Update(),
+        ConditionExpression:                 expr.Condition(),
+        ExpressionAttributeNames:            expr.Names(),
+        ExpressionAttributeValues:           expr.Values(),
+        ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+    }
+    op.conditionFailed = func(current map[string]types.AttributeValue) error {
+        if current == nil || version == nil || s.foreign(current) {
+            return ErrNotFound
+        }
+        return s.version.conflict(*version, current)
+    }
+    return op
+}
+
+// TransactDelete is Delete as a transaction operation. On an entity store
+// an item of another entity under key fails it with ErrNotFound.
+func (s *Store[T]) TransactDelete(key Key) TransactOp {
+    return s.transactDelete(key, nil)
+}
+
+// TransactDeleteIfVersion is DeleteIfVersion as a transaction operation.
+func (s *Store[T]) TransactDeleteIfVersion(key Key, version int64) TransactOp {
+    if err := s.requireVersion(); err != nil {
+        return TransactOp{name: "Delete " + s.kind, err: err}
+    }
+    return s.transactDelete(key, &version)
+}
+
+func (s *Store[T]) transactDelete(key Key, version *int64) TransactOp {
+    op := TransactOp{name: "Delete " + s.kind}
+    dynamoKey, err := s.key(key)
+    if err != nil {
+        op.err = err
+        return op
+    }
+    op.item.Delete = &types.Delete{TableName: aws.String(s.kind), Key: dynamoKey}
+
+    condition, ok := s.deleteCondition(version)
+    if !ok {
+        return op
+    }
+    expr, err := expression.NewBuilder().WithCondition(condition).Build()
+    if err != nil {
+        op.err = fmt.Errorf("error to building expression: %w", err)
+        return op
+    }
+    op.item.Delete.ConditionExpression = expr.Condition()
+    op.item.Delete.ExpressionAttributeNames = expr.Names()
+    op.item.Delete.ExpressionAttributeValues = expr.Values()
+    op.item.Delete.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+    op.conditionFailed = func(current map[string]types.AttributeValue) error {
+        if err := s.deleteFailed(version, current); err != nil {
+            return err
+        }
+        return ErrNotFound
+    }
+    return op
+}
+
+// TransactCheck makes the transaction fail unless the item with key matches
+// every filter. Only filters DynamoDB evaluates exactly are accepted, so
+// MatchSubset and groups containing it are not.
+func (s *Store[T]) TransactCheck(key Key, filters ...Filter) TransactOp {
+    op := TransactOp{name: "ConditionCheck " + s.kind}
+    dynamoKey, err := s.key(key)
+    if err != nil {
+        op.err = err
+        return op
+    }
+
+    condition, hasCondition, verify, err := buildFilterExpression(s.times.encodeFilters(filters))
+    switch {
+    case err != nil:
+        op.err = fmt.Errorf("invalid filters: %w", err)
+        return op
+    case !hasCondition:
+        op.err = errors.New("condition check without filters")
+        return op
+    case verify:
+        op.err = errors.New("condition check filters cannot be evaluated exactly by DynamoDB")
+        return op
+    }
+    expr, err := expression.NewBuilder().WithCondition(condition).Build()
+    if err != nil {
+        op.err = fmt.Errorf("error to building expression: %w", err)
+        return op
+    }
+
+    op.item.ConditionCheck = &types.ConditionCheck{
+        TableName:                           aws.String(s.kind),
+        Key:                                 dynamoKey,
+        ConditionExpression:                 expr.Condition(),
+        ExpressionAttributeNames:            expr.Names(),
+        ExpressionAttributeValues:           expr.Values(),
+        ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+    }
+    return op
+}
+
+// TransactFailure is an operation that made DynamoDB cancel a transaction.
+// Index is its position in the transaction. Err is a *ConflictError or
+// ErrNotFound when a versioned write or an update found the item in another
+// state, ErrConditionFailed for a TransactCheck, and DynamoDB's cancellation
+// code and message otherwise.
+type TransactFailure struct {
+    Index     int
+    Operation string
+    Err       error
+}
+
+// TransactionError is returned when DynamoDB cancels a transaction.
+type TransactionError struct {
+    Failures []TransactFailure
+    Err      error
+}
+
+func (e *TransactionError) Error() string {
+    if len(e.Failures) == 0 {
+        return fmt.Sprintf("transaction canceled: %v", e.Err)
+    }
+    first := e.Failures[0]
+    message := fmt.Sprintf("transaction canceled: operation %d (%s): %v", first.Index, first.Operation, first.Err)
+    if len(e.Failures) > 1 {
+        message += fmt.Sprintf(" (and %d more)", len(e.Failures)-1)
+    }
+    return message
+}
+
+func (e *TransactionError) Unwrap() []error {
+    errs := []error{e.Err}
+    for _, failure := range e.Failures {
+        errs = append(errs, failure.Err)
+    }
+    return errs
+}
+
+// WriteTransaction collects operations on any number of tables and applies
+// them all or none with TransactWriteItems.
+type WriteTransaction struct {
+    client dynamoTransactClient
+    ops    []TransactOp
+}
+
+func NewWriteTransaction(client dynamoTransactClient) *WriteTransaction {
+    return &WriteTransaction{client: client}
+}
+
+// Add appends ops to the transaction.
+func (t *WriteTransaction) Add(ops ...TransactOp) *WriteTransaction {
+    t.ops = append(t.ops, ops...)
+    return t
+}
+
+// Execute runs the transaction. When DynamoDB cancels it the error is a
+// *TransactionError naming the operations responsible.
+func (t *WriteTransaction) Execute(ctx context.Context) error {
+    if len(t.ops) == 0 || len(t.ops) > maxTransactItems {
+        return fmt.Errorf("a transaction takes 1 to %d operations, got %d", maxTransactItems, len(t.ops))
+    }
+
+    items := make([]types.TransactWriteItem, len(t.ops))
+    for i, op := range t.ops {
+        if op.err != nil {
+            return fmt.Errorf("operation %d (%s): %w", i, op.name, op.err)
+        }
+        items[i] = op.item
+    }
+
+    _, err := t.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+    var canceled *types.TransactionCanceledException
+    if errors.As(err, &canceled) {
+        return t.cancellation(canceled)
+    }
+    if err != nil {
+        return fmt.Errorf("failed to write transaction: %w", err)
+    }
+    return nil
+}
+
+// cancellation maps the reasons of a canceled transaction, listed in the
+// order of its operations, back to the operations.
+func (t *WriteTransaction) cancellation(canceled *types.TransactionCanceledException) error {
+    names := make([]string, len(t.ops))
+    for i, op := range t.ops {
+        names[i] = op.name
+    }
+    return transactionError(canceled, names, func(i int, reason types.CancellationReason) error {
+        if aws.ToString(reason.Code) != "ConditionalCheckFailed" {
+            return nil
+        }
+        if t.ops[i].conditionFailed != nil {
+            return t.ops[i].conditionFailed(reason.Item)
+        }
+        return ErrConditionFailed
+    })
+}
+
+// transactionError lists the operations, named by names, that caused a
+// cancellation. known gives the error of a reason, or nil to report its
+// code and message.
+func transactionError(canceled *types.TransactionCanceledException, names []string, known func(int, types.CancellationReason) error) *TransactionError {
+    txErr := &TransactionError{Err: canceled}
+    for i, reason := range canceled.CancellationReasons {
+        code := aws.ToString(reason.Code)
+        if code == "" || code == "None" || i >= len(names) {
+            continue
+        }
+
+        err := known(i, reason)
+        if err == nil {
+            err = fmt.Errorf("%s: %s", code, aws.ToString(reason.Message))
+        }
+        txErr.Failures = append(txErr.Failures, TransactFailure{Index: i, Operation: names[i], Err: err})
+    }
+    return txErr
+}
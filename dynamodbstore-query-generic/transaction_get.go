@@ -0,0 +1,100 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "fmt"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TransactRead is one read of a ReadTransaction, built by the TransactGet
+// method of the Store of its table.
+type TransactRead struct {
+    name   string
+    item   types.TransactGetItem
+    err    error
+    decode func(record map[string]types.AttributeValue) error
+}
+
+// TransactGet reads the item with key into item as part of a
+// ReadTransaction. item is left untouched when the key does not exist.
+func (s *Store[T]) TransactGet(key Key, item *T) TransactRead {
+    read := TransactRead{name: "Get " + s.kind}
+    dynamoKey, err := s.key(key)
+    if err != nil {
+        read.err = err
+        return read
+    }
+    if item == nil {
+        read.err = errors.New("nil destination")
+        return read
+    }
+
+    read.item.Get = &types.Get{TableName: aws.String(s.kind), Key: dynamoKey}
+    read.decode = func(record map[string]types.AttributeValue) error {
+        return s.times.unmarshalRecord(record, item)
+    }
+    return read
+}
+
+// ReadTransaction reads items of any number of tables and types as a single
+// snapshot with TransactGetItems.
+type ReadTransaction struct {
+    client dynamoTransactClient
+    reads  []TransactRead
+}
+
+func NewReadTransaction(client dynamoTransactClient) *ReadTransaction {
+    return &ReadTransaction{client: client}
+}
+
+// Add appends reads to the transaction.
+func (t *ReadTransaction) Add(reads ...TransactRead) *ReadTransaction {
+    t.reads = append(t.reads, reads...)
+    return t
+}
+
+// Execute runs the transaction and decodes every item into the destination
+// given to its TransactGet. found follows the order of the reads. When
+// DynamoDB cancels the transaction the error is a *TransactionError.
+func (t *ReadTransaction) Execute(ctx context.Context) (found []bool, err error) {
+    if len(t.reads) == 0 || len(t.reads) > maxTransactItems {
+        return nil, fmt.Errorf("a transaction takes 1 to %d operations, got %d", maxTransactItems, len(t.reads))
+    }
+
+    items := make([]types.TransactGetItem, len(t.reads))
+    names := make([]string, len(t.reads))
+    for i, read := range t.reads {
+        if read.err != nil {
+            return nil, fmt.Errorf("operation %d (%s): %w", i, read.name, read.err)
+        }
+        items[i], names[i] = read.item, read.name
+    }
+
+    output, err := t.client.TransactGetItems(ctx, &dynamodb.TransactGetItemsInput{TransactItems: items})
+    var canceled *types.TransactionCanceledException
+    if errors.As(err, &canceled) {
+        return nil, transactionError(canceled, names, func(int, types.CancellationReason) error { return nil })
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to read transaction: %w", err)
+    }
+    if len(output.Responses) != len(t.reads) {
+        return nil, fmt.Errorf("transaction returned %d responses for %d reads", len(output.Responses), len(t.reads))
+    }
+
+    found = make([]bool, len(t.reads))
+    for i, response := range output.Responses {
+        if response.Item == nil {
+            continue
+        }
+        if err := t.reads[i].decode(response.Item); err != nil {
+            return nil, fmt.Errorf("failed to decode record %d (%s): %w", i, names[i], err)
+        }
+        found[i] = true
+    }
+    return found, nil
+}
@@ -0,0 +1,81 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestReadTransaction(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("TransactGetItems", ctx, mock.Anything).Return(&dynamodb.TransactGetItemsOutput{
+        Responses: []types.ItemResponse{
+            {Item: map[string]types.AttributeValue{
+                "SpiffeID": &types.AttributeValueMemberS{Value: "spiffe://example.org/workload"},
+                "ParentID": &types.AttributeValueMemberS{Value: "spiffe://example.org/node"},
+            }},
+            {Item: bundleItem("example.org")},
+            {},
+        },
+    }, nil)
+
+    entries, err := NewStore[Entry]("EntriesTable", mockClient, "SpiffeID", "")
+    assert.NoError(t, err)
+    bundles := newBundleStore(t, mockClient)
+
+    var entry Entry
+    var bundle, missing Bundle
+    found, err := NewReadTransaction(mockClient).Add(
+        entries.TransactGet(Key{Partition: "spiffe://example.org/workload"}, &entry),
+        bundles.TransactGet(Key{Partition: "example.org"}, &bundle),
+        bundles.TransactGet(Key{Partition: "other.org"}, &missing),
+    ).Execute(ctx)
+    assert.NoError(t, err)
+    assert.Equal(t, []bool{true, true, false}, found)
+    assert.Equal(t, Entry{SpiffeID: "spiffe://example.org/workload", ParentID: "spiffe://example.org/node"}, entry)
+    assert.Equal(t, Bundle{ID: "example.org"}, bundle)
+    assert.Equal(t, Bundle{}, missing)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.TransactGetItemsInput)
+    assert.Equal(t, "EntriesTable", *input.TransactItems[0].Get.TableName)
+    assert.Equal(t, bundleItem("other.org"), input.TransactItems[2].Get.Key)
+}
+
+func TestReadTransactionCanceled(t *testing.T) {
+    ctx := context.Background()
+    code := func(code string) *string { return &code }
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("TransactGetItems", ctx, mock.Anything).Return((*dynamodb.TransactGetItemsOutput)(nil), &types.TransactionCanceledException{
+        CancellationReasons: []types.CancellationReason{{Code: code("None")}, {Code: code("TransactionConflict"), Message: code("in use")}},
+    })
+
+    store := newBundleStore(t, mockClient)
+    var a, b Bundle
+    _, err := NewReadTransaction(mockClient).Add(
+        store.TransactGet(Key{Partition: "a"}, &a),
+        store.TransactGet(Key{Partition: "b"}, &b),
+    ).Execute(ctx)
+
+    var txErr *TransactionError
+    assert.ErrorAs(t, err, &txErr)
+    assert.Len(t, txErr.Failures, 1)
+    assert.Equal(t, 1, txErr.Failures[0].Index)
+    assert.EqualError(t, txErr.Failures[0].Err, "TransactionConflict: in use")
+}
+
+func TestReadTransactionRejectsInvalidReads(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    store := newBundleStore(t, mockClient)
+
+    _, err := NewReadTransaction(mockClient).Execute(ctx)
+    assert.Error(t, err)
+    _, err = NewReadTransaction(mockClient).Add(store.TransactGet(Key{Partition: "a"}, nil)).Execute(ctx)
+    assert.Error(t, err)
+    mockClient.AssertNotCalled(t, "TransactGetItems", mock.Anything, mock.Anything)
+}
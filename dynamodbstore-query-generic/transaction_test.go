@@ -0,0 +1,89 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestWriteTransaction(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("TransactWriteItems", ctx, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+    relationships, err := NewStore[FederationRelationship]("FederationRelationshipsTable", mockClient, "TrustDomain", "")
+    assert.NoError(t, err)
+    bundles, err := NewStore[VersionedBundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+
+    err = NewWriteTransaction(mockClient).
+        Add(relationships.TransactPut(FederationRelationship{TrustDomain: "example.org", BundleURL: "https://example.org/bundle"})).
+        Add(bundles.TransactPut(VersionedBundle{ID: "example.org"})).
+        Add(relationships.TransactCheck(Key{Partition: "other.org"}, Filter{Name: "TrustDomain", Op: AttributeExists})).
+        Execute(ctx)
+    assert.NoError(t, err)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.TransactWriteItemsInput)
+    assert.Len(t, input.TransactItems, 3)
+    assert.Equal(t, "FederationRelationshipsTable", *input.TransactItems[0].Put.TableName)
+    assert.Nil(t, input.TransactItems[0].Put.ConditionExpression)
+    assert.Equal(t, &types.AttributeValueMemberN{Value: "1"}, input.TransactItems[1].Put.Item["rev"])
+    assert.Contains(t, *input.TransactItems[1].Put.ConditionExpression, "attribute_not_exists")
+    assert.Contains(t, *input.TransactItems[2].ConditionCheck.ConditionExpression, "attribute_exists")
+}
+
+func TestWriteTransactionCancellationReasons(t *testing.T) {
+    ctx := context.Background()
+    code := func(code string) *string { return &code }
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("TransactWriteItems", ctx, mock.Anything).Return((*dynamodb.TransactWriteItemsOutput)(nil), &types.TransactionCanceledException{
+        CancellationReasons: []types.CancellationReason{
+            {Code: code("None")},
+            {Code: code("ConditionalCheckFailed"), Item: map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "example.org"}, "rev": &types.AttributeValueMemberN{Value: "3"}}},
+            {Code: code("ConditionalCheckFailed")},
+            {Code: code("TransactionConflict"), Message: code("conflicting transaction")},
+        },
+    })
+
+    relationships, err := NewStore[FederationRelationship]("FederationRelationshipsTable", mockClient, "TrustDomain", "")
+    assert.NoError(t, err)
+    bundles, err := NewStore[VersionedBundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+
+    err = NewWriteTransaction(mockClient).Add(
+        relationships.TransactPut(FederationRelationship{TrustDomain: "example.org"}),
+        bundles.TransactUpdateIfVersion(Key{Partition: "example.org"}, 2, Change{Name: "Name", Op: SetValue, Value: "example"}),
+        relationships.TransactCheck(Key{Partition: "other.org"}, Filter{Name: "BundleURL", Op: BeginsWith, Value: "https://"}),
+        relationships.TransactDelete(Key{Partition: "old.org"}),
+    ).Execute(ctx)
+
+    var txErr *TransactionError
+    assert.ErrorAs(t, err, &txErr)
+    assert.Len(t, txErr.Failures, 3)
+    assert.Equal(t, TransactFailure{Index: 1, Operation: "Update BundlesTable", Err: &ConflictError{Expected: 2, Current: 3}}, txErr.Failures[0])
+    assert.Equal(t, ErrConditionFailed, txErr.Failures[1].Err)
+    assert.Equal(t, 3, txErr.Failures[2].Index)
+    assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestWriteTransactionRejectsInvalidOperations(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    store := newBundleStore(t, mockClient)
+
+    assert.Error(t, NewWriteTransaction(mockClient).Execute(ctx))
+    assert.Error(t, NewWriteTransaction(mockClient).Add(store.TransactUpdate(Key{Partition: "a"})).Execute(ctx))
+    assert.Error(t, NewWriteTransaction(mockClient).Add(store.TransactDeleteIfVersion(Key{Partition: "a"}, 1)).Execute(ctx))
+    assert.Error(t, NewWriteTransaction(mockClient).Add(store.TransactCheck(Key{Partition: "a"}, Filter{Name: "Name", Op: MatchSubset, Value: []string{"x"}})).Execute(ctx))
+
+    tx := NewWriteTransaction(mockClient)
+    for range maxTransactItems + 1 {
+        tx.Add(store.TransactDelete(Key{Partition: "a"}))
+    }
+    assert.Error(t, tx.Execute(ctx))
+    mockClient.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,123 @@
+package dynamodbstore
+
+import (
+    "fmt"
+    "reflect"
+    "strconv"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// defaultTTLAttribute stores the expiry of a field tagged
+// `dynamodbstore:"ttl"` without an attribute name.
+const defaultTTLAttribute = "TTL"
+
+// ttlField is the time.Time field tagged `dynamodbstore:"ttl"`. The field
+// keeps its own encoding and every write through Store also stores its Unix
+// epoch seconds as a number in epoch, the attribute DynamoDB Time to Live
+// reads. epoch is "TTL" unless the tag names it, as in
+// `dynamodbstore:"ttl=ExpiresAtEpoch"`. A zero time stores no epoch, so the
+// item never expires.
+type ttlField struct {
+    taggedField
+    epoch string
+}
+
+func findTTLField(itemType reflect.Type) (*ttlField, error) {
+    var found *ttlField
+    for _, field := range taggedFields(itemType) {
+        epoch, ok := field.option("ttl")
+        if !ok {
+            continue
+        }
+        if found != nil {
+            return nil, fmt.Errorf("%v has more than one ttl field", itemType)
+        }
+        if fieldType := itemType.FieldByIndex(field.index).Type; fieldType != timeType && fieldType != reflect.PointerTo(timeType) {
+            return nil, fmt.Errorf("ttl field %s of %v must be a time.Time, not %v", field.name, itemType, fieldType)
+        }
+        if epoch == "" {
+            epoch = defaultTTLAttribute
+        }
+        if itemAttributes(itemType)[epoch] {
+            return nil, fmt.Errorf("ttl attribute %q of %v is already one of its fields", epoch, itemType)
+        }
+        found = &ttlField{taggedField: field, epoch: epoch}
+    }
+    return found, nil
+}
+
+// addEpoch stores the expiry of item in record.
+func (f *ttlField) addEpoch(record map[string]types.AttributeValue, item reflect.Value) {
+    expiry, ok := expiryOf(item.FieldByIndex(f.index).Interface())
+    if !ok || expiry.IsZero() {
+        return
+    }
+    record[f.epoch] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expiry.Unix(), 10)}
+}
+
+// update keeps the epoch attribute in step with a change of the field.
+func (f *ttlField) update(update expression.UpdateBuilder, change Change) (expression.UpdateBuilder, error) {
+    epoch := expression.Name(f.epoch)
+    switch change.Op {
+    case RemoveValue:
+        return update.Remove(epoch), nil
+    case SetValue:
+        expiry, ok := expiryOf(change.Value)
+        if !ok {
+            return update, fmt.Errorf("ttl attribute %q takes a time.Time, not %T", change.Name, change.Value)
+        }
+        if expiry.IsZero() {
+            return update.Remove(epoch), nil
+        }
+        return update.Set(epoch, expression.Value(expiry.Unix())), nil
+    }
+    return update, fmt.Errorf("ttl attribute %q can only be set or removed", change.Name)
+}
+
+func expiryOf(value interface{}) (time.Time, bool) {
+    switch v := value.(type) {
+    case time.Time:
+        return v, true
+    case *time.Time:
+        if v == nil {
+            return time.Time{}, true
+        }
+        return *v, true
+    }
+    return time.Time{}, false
+}
+
+// ExcludeExpired drops the items whose ttl field is in the past, which
+// DynamoDB may keep returning for a while before deleting them. The item
+// type must have a field tagged `dynamodbstore:"ttl"`.
+func ExcludeExpired() Option {
+    return func(o *options) {
+        o.excludeExpired = true
+    }
+}
+
+// withExpiry resolves ExcludeExpired against the ttl field of T.
+func withExpiry[T any](settings options) (options, error) {
+    if !settings.excludeExpired {
+        return settings, nil
+    }
+    itemType := reflect.TypeOf((*T)(nil)).Elem()
+    field, err := findTTLField(itemType)
+    if err != nil {
+        return settings, err
+    }
+    if field == nil {
+        return settings, fmt.Errorf("%v has no field tagged %s:\"ttl\"", itemType, tagName)
+    }
+    settings.expiry = field.epoch
+    return settings, nil
+}
+
+// unexpiredCondition holds for items without an expiry or expiring after now.
+func unexpiredCondition(attribute string, now time.Time) expression.ConditionBuilder {
+    epoch := expression.Name(attribute)
+    return expression.Or(epoch.AttributeNotExists(), epoch.GreaterThan(expression.Value(now.Unix())))
+}
@@ -0,0 +1,185 @@
+package dynamodbstore
+
+import (
+    "context"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+// JoinToken whose expiry DynamoDB Time to Live enforces
+type ExpiringJoinToken struct {
+    Token     string
+    ExpiresAt time.Time `dynamodbstore:"ttl"`
+}
+
+func TestFindTTLField(t *testing.T) {
+    field, err := findTTLField(reflect.TypeOf(ExpiringJoinToken{}))
+    assert.NoError(t, err)
+    assert.Equal(t, "ExpiresAt", field.attribute)
+    assert.Equal(t, "TTL", field.epoch)
+
+    field, err = findTTLField(reflect.TypeOf(struct {
+        ExpiresAt *time.Time `dynamodbstore:"ttl=ExpiresAtEpoch"`
+    }{}))
+    assert.NoError(t, err)
+    assert.Equal(t, "ExpiresAtEpoch", field.epoch)
+
+    field, err = findTTLField(reflect.TypeOf(JoinToken{}))
+    assert.NoError(t, err)
+    assert.Nil(t, field)
+
+    for _, itemType := range []reflect.Type{
+        reflect.TypeOf(struct {
+            ExpiresAt int64 `dynamodbstore:"ttl"`
+        }{}),
+        reflect.TypeOf(struct {
+            ExpiresAt time.Time `dynamodbstore:"ttl"`
+            DeletedAt time.Time `dynamodbstore:"ttl=DeletedEpoch"`
+        }{}),
+        reflect.TypeOf(struct {
+            ExpiresAt time.Time `dynamodbstore:"ttl=Token"`
+            Token     string
+        }{}),
+    } {
+        _, err := findTTLField(itemType)
+        assert.Error(t, err, "%v", itemType)
+    }
+}
+
+func TestStoreWritesTTLEpoch(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("PutItem", ctx, mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+    mockClient.On("UpdateItem", ctx, mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil)
+    expiresAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+    store, err := NewStore[ExpiringJoinToken]("JoinTokensTable", mockClient, "Token", "")
+    assert.NoError(t, err)
+    _, err = store.Put(ctx, ExpiringJoinToken{Token: "token-1", ExpiresAt: expiresAt})
+    assert.NoError(t, err)
+    _, err = store.Put(ctx, ExpiringJoinToken{Token: "token-2"})
+    assert.NoError(t, err)
+
+    put := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.PutItemInput)
+    assert.Equal(t, &types.AttributeValueMemberN{Value: "1704067200"}, put.Item["TTL"])
+    assert.Contains(t, put.Item, "ExpiresAt")
+    put = mockClient.Calls[1].Arguments.Get(1).(*dynamodb.PutItemInput)
+    assert.NotContains(t, put.Item, "TTL")
+
+    // Changing the field changes the epoch with it
+    _, err = store.Update(ctx, Key{Partition: "token-1"}, Change{Name: "ExpiresAt", Op: SetValue, Value: expiresAt})
+    assert.NoError(t, err)
+    update := mockClient.Calls[2].Arguments.Get(1).(*dynamodb.UpdateItemInput)
+    assert.Contains(t, updateNames(update), "TTL")
+    assert.Contains(t, valuesOf(update.ExpressionAttributeValues), types.AttributeValue(&types.AttributeValueMemberN{Value: "1704067200"}))
+
+    _, err = store.Update(ctx, Key{Partition: "token-1"}, Change{Name: "ExpiresAt", Op: RemoveValue})
+    assert.NoError(t, err)
+    update = mockClient.Calls[3].Arguments.Get(1).(*dynamodb.UpdateItemInput)
+    assert.Contains(t, *update.UpdateExpression, "REMOVE")
+    assert.Contains(t, updateNames(update), "TTL")
+
+    for _, change := range []Change{
+        {Name: "TTL", Op: SetValue, Value: 1704067200},
+        {Name: "ExpiresAt", Op: SetValue, Value: "2024-01-01T00:00:00Z"},
+        {Name: "ExpiresAt", Op: AddValue, Value: 60},
+    } {
+        _, err := store.Update(ctx, Key{Partition: "token-1"}, change)
+        assert.Error(t, err, "%+v", change)
+    }
+    mockClient.AssertNumberOfCalls(t, "UpdateItem", 2)
+}
+
+// Returns the expression attribute values
+func valuesOf(values map[string]types.AttributeValue) []types.AttributeValue {
+    var list []types.AttributeValue
+    for _, value := range values {
+        list = append(list, value)
+    }
+    return list
+}
+
+// Returns the attribute names of an update
+func updateNames(input *dynamodb.UpdateItemInput) []string {
+    var names []string
+    for _, name := range input.ExpressionAttributeNames {
+        names = append(names, name)
+    }
+    return names
+}
+
+func TestListItemsExcludeExpired(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{
+        Items:            []map[string]types.AttributeValue{{"Token": &types.AttributeValueMemberS{Value: "token-1"}}},
+        LastEvaluatedKey: map[string]types.AttributeValue{"Token": &types.AttributeValueMemberS{Value: "token-1"}},
+    }, nil)
+    filters := []Filter{{Name: "Token", Op: EqualTo, Value: "token-1"}}
+
+    pagination := &Pagination{Limit: 1}
+    _, _, err := ListItems[ExpiringJoinToken](ctx, "JoinTokensTable", mockClient, "Token", "", filters, pagination, nil, ExcludeExpired())
+    assert.NoError(t, err)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Contains(t, *input.FilterExpression, "attribute_not_exists")
+    assert.Contains(t, attributeNames(input), "TTL")
+
+    // The next page is accepted although the clock moved on
+    pagination = &Pagination{Limit: 1, Token: pagination.NextToken}
+    _, _, err = ListItems[ExpiringJoinToken](ctx, "JoinTokensTable", mockClient, "Token", "", filters, pagination, nil, ExcludeExpired())
+    assert.NoError(t, err)
+
+    // Without the option the token belongs to another query
+    _, _, err = ListItems[ExpiringJoinToken](ctx, "JoinTokensTable", mockClient, "Token", "", filters, pagination, nil)
+    assert.ErrorIs(t, err, ErrInvalidToken)
+
+    _, _, err = ListItems[JoinToken](ctx, "JoinTokensTable", mockClient, "Token", "", filters, nil, nil, ExcludeExpired())
+    assert.Error(t, err)
+    _, err = CountItemsOf[JoinToken](ctx, "JoinTokensTable", mockClient, "Token", "", filters, ExcludeExpired())
+    assert.Error(t, err)
+    mockClient.AssertNumberOfCalls(t, "Query", 2)
+}
+
+func TestCountItemsExcludeExpired(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Count: 1, ScannedCount: 2}, nil)
+    filters := []Filter{{Name: "Token", Op: EqualTo, Value: "token-1"}}
+
+    count, err := CountItemsOf[ExpiringJoinToken](ctx, "JoinTokensTable", mockClient, "Token", "", filters, ExcludeExpired())
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Equal(t, ItemCount{Count: 1, ScannedCount: 2}, count)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Equal(t, types.SelectCount, input.Select)
+    assert.Contains(t, *input.FilterExpression, "attribute_not_exists")
+    assert.Contains(t, attributeNames(input), "TTL")
+
+    // CountItems has no item type to find the ttl field in
+    _, err = CountItems(ctx, "JoinTokensTable", mockClient, "Token", "", filters, ExcludeExpired())
+    assert.ErrorContains(t, err, "CountItemsOf")
+    mockClient.AssertNumberOfCalls(t, "Query", 1)
+}
+
+func TestRegisterTTLField(t *testing.T) {
+    registry := NewRegistry()
+    assert.NoError(t, Register[ExpiringJoinToken](registry, Schema{Table: "JoinTokensTable", PartitionKey: "Token"}))
+    assert.Error(t, Register[ExpiringJoinToken](NewRegistry(), Schema{Table: "JoinTokensTable", PartitionKey: "Token", TTLAttribute: "Expiry"}))
+
+    definitions, err := registry.tableDefinitions()
+    assert.NoError(t, err)
+    assert.Equal(t, "TTL", definitions[0].ttl)
+
+    s, err := lookup[ExpiringJoinToken](registry)
+    assert.NoError(t, err)
+    assert.NoError(t, s.validateFilters([]Filter{{Name: "TTL", Op: LessThan, Value: 1704067200}}))
+}
@@ -0,0 +1,92 @@
+package dynamodbstore
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrConflict matches every *ConflictError.
+var ErrConflict = errors.New("version conflict")
+
+// ConflictError is returned by a versioned write when the item is no longer
+// at the version the caller read. Current is the version found in the table,
+// 0 when the item does not exist or was never written with a version.
+type ConflictError struct {
+    Expected int64
+    Current  int64
+}
+
+func (e *ConflictError) Error() string {
+    return fmt.Sprintf("%v: expected version %d, found %d", ErrConflict, e.Expected, e.Current)
+}
+
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+// versionField is the integer field tagged `dynamodbstore:"version"`. Every
+// write through Store increments it, and Put, UpdateIfVersion and
+// DeleteIfVersion only succeed while the stored item still has the version
+// the caller holds. Version 0 stands for an item that does not exist yet.
+type versionField struct {
+    taggedField
+}
+
+func findVersionField(itemType reflect.Type) (*versionField, error) {
+    var found *versionField
+    for _, field := range taggedFields(itemType) {
+        if !field.hasOption("version") {
+            continue
+        }
+        if found != nil {
+            return nil, fmt.Errorf("%v has more than one version field", itemType)
+        }
+        switch field.kind {
+        case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+        default:
+            return nil, fmt.Errorf("version field %s of %v must be an integer, not %v", field.name, itemType, field.kind)
+        }
+        found = &versionField{field}
+    }
+    return found, nil
+}
+
+func (f *versionField) get(item reflect.Value) int64 {
+    value := item.FieldByIndex(f.index)
+    if value.CanInt() {
+        return value.Int()
+    }
+    return int64(value.Uint())
+}
+
+func (f *versionField) set(item reflect.Value, version int64) {
+    value := item.FieldByIndex(f.index)
+    if value.CanInt() {
+        value.SetInt(version)
+        return
+    }
+    value.SetUint(uint64(version))
+}
+
+// condition holds while the stored item is at version.
+func (f *versionField) condition(version int64) expression.ConditionBuilder {
+    field := expression.Name(f.attribute)
+    if version == 0 {
+        return field.AttributeNotExists()
+    }
+    return field.Equal(expression.Value(version))
+}
+
+// conflict builds the error for a failed version condition from the item
+// DynamoDB returned with the failure. A version that cannot be decoded is
+// reported as 0.
+func (f *versionField) conflict(expected int64, current map[string]types.AttributeValue) *ConflictError {
+    conflict := &ConflictError{Expected: expected}
+    if value, ok := current[f.attribute]; ok {
+        attributevalue.Unmarshal(value, &conflict.Current)
+    }
+    return conflict
+}
@@ -0,0 +1,136 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+type VersionedBundle struct {
+    ID       string
+    Name     string
+    Revision int64 `dynamodbstore:"version" dynamodbav:"rev"`
+}
+
+func TestVersionedPut(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("PutItem", ctx, mock.Anything).Return(&dynamodb.PutItemOutput{}, nil)
+
+    store, err := NewStore[VersionedBundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+
+    // Version 0 creates the item
+    bundle, err := store.Put(ctx, VersionedBundle{ID: "bundle-1", Name: "example.org"})
+    assert.NoError(t, err)
+    assert.Equal(t, int64(1), bundle.Revision)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.PutItemInput)
+    assert.Contains(t, *input.ConditionExpression, "attribute_not_exists")
+    assert.Equal(t, &types.AttributeValueMemberN{Value: "1"}, input.Item["rev"])
+
+    bundle, err = store.Put(ctx, bundle)
+    assert.NoError(t, err)
+    assert.Equal(t, int64(2), bundle.Revision)
+
+    input = mockClient.Calls[1].Arguments.Get(1).(*dynamodb.PutItemInput)
+    assert.Contains(t, *input.ConditionExpression, "=")
+    assert.Equal(t, &types.AttributeValueMemberN{Value: "1"}, input.ExpressionAttributeValues[":0"])
+    assert.Equal(t, &types.AttributeValueMemberN{Value: "2"}, input.Item["rev"])
+}
+
+func TestVersionedPutConflict(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("PutItem", ctx, mock.Anything).Return((*dynamodb.PutItemOutput)(nil), &types.ConditionalCheckFailedException{
+        Item: map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "bundle-1"}, "rev": &types.AttributeValueMemberN{Value: "5"}},
+    })
+
+    store, err := NewStore[VersionedBundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+
+    item, err := store.Put(ctx, VersionedBundle{ID: "bundle-1", Revision: 4})
+    assert.ErrorIs(t, err, ErrConflict)
+    assert.Equal(t, int64(4), item.Revision)
+
+    var conflict *ConflictError
+    assert.ErrorAs(t, err, &conflict)
+    assert.Equal(t, &ConflictError{Expected: 4, Current: 5}, conflict)
+}
+
+func TestVersionedUpdate(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("UpdateItem", ctx, mock.Anything).Return(&dynamodb.UpdateItemOutput{Attributes: map[string]types.AttributeValue{
+        "ID":   &types.AttributeValueMemberS{Value: "bundle-1"},
+        "Name": &types.AttributeValueMemberS{Value: "renamed.org"},
+        "rev":  &types.AttributeValueMemberN{Value: "3"},
+    }}, nil).Once()
+
+    store, err := NewStore[VersionedBundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+
+    bundle, err := store.UpdateIfVersion(ctx, Key{Partition: "bundle-1"}, 2, Change{Name: "Name", Op: SetValue, Value: "renamed.org"})
+    assert.NoError(t, err)
+    assert.Equal(t, int64(3), bundle.Revision)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.UpdateItemInput)
+    assert.Contains(t, *input.UpdateExpression, "ADD")
+    assert.Contains(t, *input.ConditionExpression, "attribute_exists")
+    assert.Contains(t, *input.ConditionExpression, "AND")
+    var names []string
+    for _, name := range input.ExpressionAttributeNames {
+        names = append(names, name)
+    }
+    assert.ElementsMatch(t, []string{"ID", "Name", "rev"}, names)
+
+    mockClient.On("UpdateItem", ctx, mock.Anything).Return((*dynamodb.UpdateItemOutput)(nil), &types.ConditionalCheckFailedException{
+        Item: map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "bundle-1"}, "rev": &types.AttributeValueMemberN{Value: "3"}},
+    }).Once()
+    _, err = store.UpdateIfVersion(ctx, Key{Partition: "bundle-1"}, 2, Change{Name: "Name", Op: SetValue, Value: "other.org"})
+    assert.Equal(t, &ConflictError{Expected: 2, Current: 3}, err)
+
+    _, err = store.Update(ctx, Key{Partition: "bundle-1"}, Change{Name: "rev", Op: SetValue, Value: 9})
+    assert.Error(t, err)
+}
+
+func TestVersionedDelete(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("DeleteItem", ctx, mock.Anything).Return((*dynamodb.DeleteItemOutput)(nil), &types.ConditionalCheckFailedException{})
+
+    store, err := NewStore[VersionedBundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+
+    // The item is gone: the conflict reports version 0
+    err = store.DeleteIfVersion(ctx, Key{Partition: "bundle-1"}, 2)
+    assert.Equal(t, &ConflictError{Expected: 2}, err)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.DeleteItemInput)
+    assert.Equal(t, types.ReturnValuesOnConditionCheckFailureAllOld, input.ReturnValuesOnConditionCheckFailure)
+
+    bundles, err := NewStore[Bundle]("BundlesTable", mockClient, "ID", "")
+    assert.NoError(t, err)
+    assert.Error(t, bundles.DeleteIfVersion(ctx, Key{Partition: "bundle-1"}, 2))
+}
+
+func TestNewStoreRejectsInvalidVersionFields(t *testing.T) {
+    type textVersion struct {
+        ID      string
+        Version string `dynamodbstore:"version"`
+    }
+    type twoVersions struct {
+        ID string
+        A  int `dynamodbstore:"version"`
+        B  int `dynamodbstore:"version"`
+    }
+
+    _, err := NewStore[textVersion]("Table", nil, "ID", "")
+    assert.Error(t, err)
+    _, err = NewStore[twoVersions]("Table", nil, "ID", "")
+    assert.Error(t, err)
+}
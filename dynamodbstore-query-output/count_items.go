@@ -0,0 +1,79 @@
+package dynamodbstore
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ItemCount is the result of CountItems.
+type ItemCount struct {
+    Count        int64 // items matching the filters
+    ScannedCount int64 // items read before the filter expression
+}
+
+// CountItems counts the items ListItems would return for the same filters,
+// following every page without decoding them. It uses Select COUNT; when a
+// filter needs local verification it reads only the attributes the filters
+// use and counts the matches itself.
+func CountItems(
+    ctx context.Context,
+    kind string,
+    dynamoClient dynamoQueryClient,
+    partitionKey string,
+    sortKey string,
+    filters []Filter,
+    opts ...Option,
+) (ItemCount, error) {
+
+    settings := newOptions(opts)
+    request, err := newQueryRequest(kind, partitionKey, sortKey, filters, nil, false, settings)
+    if err != nil {
+        return ItemCount{}, err
+    }
+    if request.verify {
+        if request, err = newQueryRequest(kind, partitionKey, sortKey, filters, countProjection(request.remaining), false, settings); err != nil {
+            return ItemCount{}, err
+        }
+    } else {
+        request.input.Select = types.SelectCount
+    }
+
+    var count ItemCount
+    queryPaginator := dynamodb.NewQueryPaginator(dynamoClient, request.input)
+    for queryPaginator.HasMorePages() {
+        page, err := queryPaginator.NextPage(ctx)
+        if err != nil {
+            return ItemCount{}, fmt.Errorf("failed to count records: %w", err)
+        }
+        count.ScannedCount += int64(page.ScannedCount)
+
+        if !request.verify {
+            count.Count += int64(page.Count)
+            continue
+        }
+        items, err := request.verifyPage(page.Items)
+        if err != nil {
+            return ItemCount{}, err
+        }
+        count.Count += int64(len(items))
+    }
+    return count, nil
+}
+
+// countProjection lists the top-level attributes read by filters.
+func countProjection(filters []Filter) []string {
+    seen := make(map[string]bool)
+    var names []string
+    for _, name := range filterNames(And(filters...)) {
+        name = strings.Split(name, ".")[0]
+        if !seen[name] {
+            seen[name] = true
+            names = append(names, name)
+        }
+    }
+    return names
+}
@@ -0,0 +1,352 @@
+package dynamodbstore
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
+
+// Combinator joins the children of a group Filter.
+type Combinator int
+
+const (
+    CombineAnd Combinator = iota + 1
+    CombineOr
+    CombineNot
+)
+
+// And matches items satisfying every filter.
+func And(filters ...Filter) Filter {
+    return Filter{Combinator: CombineAnd, Filters: filters}
+}
+
+// Or matches items satisfying at least one filter.
+func Or(filters ...Filter) Filter {
+    return Filter{Combinator: CombineOr, Filters: filters}
+}
+
+// Not matches items not satisfying filter.
+func Not(filter Filter) Filter {
+    return Filter{Combinator: CombineNot, Filters: []Filter{filter}}
+}
+
+// filterBounds approximates a filter with conditions DynamoDB can evaluate.
+// Every item matching the filter satisfies relaxed and every item satisfying
+// strict matches the filter. A missing relaxed bound stands for TRUE and a
+// missing strict bound for FALSE. exact is set when both bounds are the
+// filter itself, so no local verification is needed.
+type filterBounds struct {
+    relaxed, strict       expression.ConditionBuilder
+    hasRelaxed, hasStrict bool
+    exact                 bool
+}
+
+func exactBounds(condition expression.ConditionBuilder) filterBounds {
+    return filterBounds{relaxed: condition, strict: condition, hasRelaxed: true, hasStrict: true, exact: true}
+}
+
+// buildFilterExpression ANDs filters together into a single condition. When
+// verify is set the condition only narrows the read and returned items must
+// still be checked with matchFilters.
+func buildFilterExpression(filters []Filter) (condition expression.ConditionBuilder, hasCondition, verify bool, err error) {
+    if len(filters) == 0 {
+        return condition, false, false, nil
+    }
+
+    bounds, err := filterBoundsOf(And(filters...))
+    if err != nil {
+        return condition, false, false, err
+    }
+    return bounds.relaxed, bounds.hasRelaxed, !bounds.exact, nil
+}
+
+func filterBoundsOf(filter Filter) (filterBounds, error) {
+    switch filter.Combinator {
+    case 0:
+        return leafBounds(filter)
+    case CombineNot:
+        child, err := filterBoundsOf(And(filter.Filters...))
+        if err != nil {
+            return filterBounds{}, err
+        }
+        return filterBounds{
+            relaxed:    expression.Not(child.strict),
+            strict:     expression.Not(child.relaxed),
+            hasRelaxed: child.hasStrict,
+            hasStrict:  child.hasRelaxed,
+            exact:      child.exact,
+        }, nil
+    case CombineAnd, CombineOr:
+        children := make([]filterBounds, 0, len(filter.Filters))
+        for _, child := range filter.Filters {
+            bounds, err := filterBoundsOf(child)
+            if err != nil {
+                return filterBounds{}, err
+            }
+            children = append(children, bounds)
+        }
+
+        switch len(children) {
+        case 0:
+            return filterBounds{}, errors.New("empty filter group")
+        case 1:
+            return children[0], nil
+        }
+
+        var relaxed, strict []expression.ConditionBuilder
+        bounds := filterBounds{exact: true}
+        for _, child := range children {
+            if child.hasRelaxed {
+                relaxed = append(relaxed, child.relaxed)
+            }
+            if child.hasStrict {
+                strict = append(strict, child.strict)
+            }
+            bounds.exact = bounds.exact && child.exact
+        }
+
+        if filter.Combinator == CombineOr {
+            bounds.relaxed, bounds.hasRelaxed = combine(expression.Or, relaxed), len(relaxed) == len(children)
+            bounds.strict, bounds.hasStrict = combine(expression.Or, strict), len(strict) > 0
+        } else {
+            bounds.relaxed, bounds.hasRelaxed = combine(expression.And, relaxed), len(relaxed) > 0
+            bounds.strict, bounds.hasStrict = combine(expression.And, strict), len(strict) == len(children)
+        }
+        return bounds, nil
+    }
+
+    return filterBounds{}, fmt.Errorf("unknown combinator: %d", filter.Combinator)
+}
+
+func combine(join func(left, right expression.ConditionBuilder, other ...expression.ConditionBuilder) expression.ConditionBuilder, conditions []expression.ConditionBuilder) expression.ConditionBuilder {
+    switch len(conditions) {
+    case 0:
+        return expression.ConditionBuilder{}
+    case 1:
+        return conditions[0]
+    }
+    return join(conditions[0], conditions[1], conditions[2:]...)
+}
+
+func leafBounds(filter Filter) (filterBounds, error) {
+    switch filter.Op {
+    case MatchAny, MatchSuperset, MatchSubset:
+        return setBounds(filter)
+    }
+
+    condition, err := leafCondition(filter)
+    if err != nil {
+        return filterBounds{}, err
+    }
+    return exactBounds(condition), nil
+}
+
+// setBounds compiles the selector-style operations. MatchAny and
+// MatchSuperset map onto contains() directly; DynamoDB cannot check that
+// every element of an attribute belongs to a list, so MatchSubset only keeps
+// items sharing an element with Value and leaves the rest to matchFilters.
+func setBounds(filter Filter) (filterBounds, error) {
+    values, err := setValues(filter)
+    if err != nil {
+        return filterBounds{}, err
+    }
+
+    field := expression.Name(filter.Name)
+    conditions := make([]expression.ConditionBuilder, len(values))
+    for i, value := range values {
+        conditions[i] = expression.Contains(field, value)
+    }
+
+    switch filter.Op {
+    case MatchAny:
+        return exactBounds(combine(expression.Or, conditions)), nil
+    case MatchSuperset:
+        return exactBounds(combine(expression.And, conditions)), nil
+    }
+    return filterBounds{relaxed: combine(expression.Or, conditions), hasRelaxed: true}, nil
+}
+
+// setValues returns the elements of a set or In filter's Value. A scalar
+// Value is treated as a single-element set.
+func setValues(filter Filter) ([]interface{}, error) {
+    value := reflect.ValueOf(filter.Value)
+    if !value.IsValid() {
+        return nil, fmt.Errorf("filter on %q needs a value", filter.Name)
+    }
+    if (value.Kind() != reflect.Slice && value.Kind() != reflect.Array) || value.Type().Elem().Kind() == reflect.Uint8 {
+        return []interface{}{filter.Value}, nil
+    }
+
+    values := make([]interface{}, value.Len())
+    for i := range values {
+        values[i] = value.Index(i).Interface()
+    }
+    if len(values) == 0 {
+        return nil, fmt.Errorf("filter on %q needs at least one value", filter.Name)
+    }
+    return values, nil
+}
+
+func leafCondition(filter Filter) (expression.ConditionBuilder, error) {
+    field := expression.Name(filter.Name)
+
+    switch filter.Op {
+    case EqualTo:
+        return field.Equal(expression.Value(filter.Value)), nil
+    case LessThan:
+        return field.LessThan(expression.Value(filter.Value)), nil
+    case GreaterThan:
+        return field.GreaterThan(expression.Value(filter.Value)), nil
+    case MatchExact:
+        return field.Equal(expression.Value(filter.Value)), nil
+    case LessOrEqual:
+        return field.LessThanEqual(expression.Value(filter.Value)), nil
+    case GreaterOrEqual:
+        return field.GreaterThanEqual(expression.Value(filter.Value)), nil
+    case Between:
+        bounds, err := rangeValue(filter.Name, filter.Value)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        return field.Between(expression.Value(bounds.Lower), expression.Value(bounds.Upper)), nil
+    case BeginsWith:
+        prefix, err := prefixValue(filter)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        return field.BeginsWith(prefix), nil
+    case NotEqual:
+        return field.NotEqual(expression.Value(filter.Value)), nil
+    case In:
+        values, err := setValues(filter)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        if len(values) > maxInValues {
+            return expression.ConditionBuilder{}, fmt.Errorf("in filter on %q accepts at most %d values, got %d", filter.Name, maxInValues, len(values))
+        }
+        operands := make([]expression.OperandBuilder, len(values))
+        for i, value := range values {
+            operands[i] = expression.Value(value)
+        }
+        return field.In(operands[0], operands[1:]...), nil
+    case AttributeExists:
+        return field.AttributeExists(), nil
+    case AttributeNotExists:
+        return field.AttributeNotExists(), nil
+    case AttributeType:
+        attributeType, err := typeValue(filter)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        return field.AttributeType(attributeType), nil
+    case Size:
+        comparison, err := sizeValue(filter)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        return sizeCondition(filter.Name, field.Size(), comparison)
+    }
+
+    return expression.ConditionBuilder{}, fmt.Errorf("unknown operation for %q: %d", filter.Name, filter.Op)
+}
+
+// maxInValues is the number of operands DynamoDB accepts in an IN list.
+const maxInValues = 100
+
+func sizeCondition(name string, size expression.SizeBuilder, comparison SizeComparison) (expression.ConditionBuilder, error) {
+    value := expression.Value(comparison.Value)
+
+    switch comparison.Op {
+    case EqualTo:
+        return size.Equal(value), nil
+    case NotEqual:
+        return size.NotEqual(value), nil
+    case LessThan:
+        return size.LessThan(value), nil
+    case LessOrEqual:
+        return size.LessThanEqual(value), nil
+    case GreaterThan:
+        return size.GreaterThan(value), nil
+    case GreaterOrEqual:
+        return size.GreaterThanEqual(value), nil
+    case Between:
+        bounds, err := rangeValue(name, comparison.Value)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        return size.Between(expression.Value(bounds.Lower), expression.Value(bounds.Upper)), nil
+    }
+
+    return expression.ConditionBuilder{}, fmt.Errorf("unsupported size comparison for %q: %d", name, comparison.Op)
+}
+
+// sortKeyCondition turns filter into a key condition when its operation is
+// one DynamoDB accepts on a sort key. ok is false for any other operation so
+// the filter can fall back to the filter expression.
+func sortKeyCondition(filter Filter) (condition expression.KeyConditionBuilder, ok bool, err error) {
+    key := expression.Key(filter.Name)
+
+    switch filter.Op {
+    case EqualTo, MatchExact:
+        return key.Equal(expression.Value(filter.Value)), true, nil
+    case LessThan:
+        return key.LessThan(expression.Value(filter.Value)), true, nil
+    case LessOrEqual:
+        return key.LessThanEqual(expression.Value(filter.Value)), true, nil
+    case GreaterThan:
+        return key.GreaterThan(expression.Value(filter.Value)), true, nil
+    case GreaterOrEqual:
+        return key.GreaterThanEqual(expression.Value(filter.Value)), true, nil
+    case Between:
+        bounds, err := rangeValue(filter.Name, filter.Value)
+        if err != nil {
+            return condition, false, err
+        }
+        return key.Between(expression.Value(bounds.Lower), expression.Value(bounds.Upper)), true, nil
+    case BeginsWith:
+        prefix, err := prefixValue(filter)
+        if err != nil {
+            return condition, false, err
+        }
+        return key.BeginsWith(prefix), true, nil
+    }
+
+    return condition, false, nil
+}
+
+func rangeValue(name string, value interface{}) (Range, error) {
+    bounds, ok := value.(Range)
+    if !ok {
+        return Range{}, fmt.Errorf("between filter on %q needs a Range value, got %T", name, value)
+    }
+    return bounds, nil
+}
+
+func prefixValue(filter Filter) (string, error) {
+    prefix, ok := filter.Value.(string)
+    if !ok {
+        return "", fmt.Errorf("begins with filter on %q needs a string value, got %T", filter.Name, filter.Value)
+    }
+    return prefix, nil
+}
+
+func typeValue(filter Filter) (expression.DynamoDBAttributeType, error) {
+    switch attributeType := filter.Value.(type) {
+    case expression.DynamoDBAttributeType:
+        return attributeType, nil
+    case string:
+        return expression.DynamoDBAttributeType(attributeType), nil
+    }
+    return "", fmt.Errorf("attribute type filter on %q needs an expression.DynamoDBAttributeType value, got %T", filter.Name, filter.Value)
+}
+
+func sizeValue(filter Filter) (SizeComparison, error) {
+    comparison, ok := filter.Value.(SizeComparison)
+    if !ok {
+        return SizeComparison{}, fmt.Errorf("size filter on %q needs a SizeComparison value, got %T", filter.Name, filter.Value)
+    }
+    return comparison, nil
+}
@@ -0,0 +1,153 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestListItemsFilterTree(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+    filters := []Filter{
+        {Name: "SpiffeID", Op: EqualTo, Value: "spiffe://example.org/node"},
+        Or(
+            Filter{Name: "ParentID", Op: EqualTo, Value: "spiffe://example.org/a"},
+            Filter{Name: "ParentID", Op: EqualTo, Value: "spiffe://example.org/b"},
+        ),
+        Not(Filter{Name: "Downstream", Op: EqualTo, Value: true}),
+    }
+    _, err := ListItems(ctx, "EntriesTable", mockClient, "SpiffeID", "", filters, nil, nil)
+    assert.NoError(t, err)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.NotNil(t, input.KeyConditionExpression)
+    assert.NotNil(t, input.FilterExpression)
+    assert.Contains(t, *input.FilterExpression, "OR")
+    assert.Contains(t, *input.FilterExpression, "NOT")
+    assert.Len(t, input.ExpressionAttributeValues, 4)
+}
+
+func TestListItemsRejectsInvalidFilters(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+
+    _, err := ListItems(ctx, "EntriesTable", mockClient, "SpiffeID", "", []Filter{And()}, nil, nil)
+    assert.Error(t, err)
+
+    mockClient.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+}
+
+func TestListItemsSortKeyConditions(t *testing.T) {
+    ctx := context.Background()
+    from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+    cases := []struct {
+        filter   Filter
+        contains string
+    }{
+        {Filter{Name: "Timestamp", Op: Between, Value: Range{Lower: from, Upper: from.Add(time.Hour)}}, "BETWEEN"},
+        {Filter{Name: "Timestamp", Op: GreaterOrEqual, Value: from}, ">="},
+        {Filter{Name: "Timestamp", Op: LessThan, Value: from}, "<"},
+        {Filter{Name: "Timestamp", Op: BeginsWith, Value: "2024-01"}, "begins_with"},
+    }
+    for _, c := range cases {
+        mockClient := new(MockDynamoDBClient)
+        mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+        filters := []Filter{{Name: "NodeID", Op: EqualTo, Value: "node1"}, c.filter}
+        _, err := ListItems(ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, nil, nil)
+        assert.NoError(t, err)
+
+        input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+        assert.Contains(t, *input.KeyConditionExpression, c.contains)
+        assert.Nil(t, input.FilterExpression)
+    }
+}
+
+func TestListItemsSortKeyFallsBackToFilter(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+    filters := []Filter{
+        {Name: "EntryID", Op: EqualTo, Value: "entry1"},
+        {Name: "EventID", Op: GreaterThan, Value: 10},
+        {Name: "EventID", Op: LessThan, Value: 20},
+    }
+    _, err := ListItems(ctx, "EntryEventsTable", mockClient, "EntryID", "EventID", filters, nil, nil)
+    assert.NoError(t, err)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Contains(t, *input.KeyConditionExpression, ">")
+    assert.Contains(t, *input.FilterExpression, "<")
+}
+
+func TestListItemsRejectsInvalidSortKeyValue(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+
+    filters := []Filter{
+        {Name: "NodeID", Op: EqualTo, Value: "node1"},
+        {Name: "Timestamp", Op: Between, Value: "2024-01-01"},
+    }
+    _, err := ListItems(ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, nil, nil)
+    assert.Error(t, err)
+}
+
+func TestListItemsExtendedOperators(t *testing.T) {
+    ctx := context.Background()
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+    cases := []struct {
+        filter   Filter
+        contains string
+    }{
+        {Filter{Name: "ExpiresAt", Op: LessOrEqual, Value: now}, "<="},
+        {Filter{Name: "ExpiresAt", Op: GreaterOrEqual, Value: now}, ">="},
+        {Filter{Name: "Banned", Op: AttributeNotExists}, "attribute_not_exists"},
+        {Filter{Name: "Banned", Op: AttributeExists}, "attribute_exists"},
+        {Filter{Name: "Token", Op: NotEqual, Value: "token123"}, "<>"},
+        {Filter{Name: "Token", Op: In, Value: []string{"a", "b", "c"}}, "IN"},
+        {Filter{Name: "Token", Op: BeginsWith, Value: "tok"}, "begins_with"},
+        {Filter{Name: "Selectors", Op: AttributeType, Value: expression.StringSet}, "attribute_type"},
+        {Filter{Name: "Selectors", Op: Size, Value: SizeComparison{Op: GreaterThan, Value: 2}}, "size"},
+        {Filter{Name: "Selectors", Op: Size, Value: SizeComparison{Op: Between, Value: Range{Lower: 1, Upper: 3}}}, "BETWEEN"},
+    }
+    for _, c := range cases {
+        mockClient := new(MockDynamoDBClient)
+        mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+        filters := []Filter{{Name: "NodeID", Op: EqualTo, Value: "node1"}, c.filter}
+        _, err := ListItems(ctx, "JoinTokensTable", mockClient, "NodeID", "", filters, nil, nil)
+        assert.NoError(t, err)
+
+        input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+        assert.Contains(t, *input.FilterExpression, c.contains)
+    }
+}
+
+func TestListItemsRejectsInvalidOperatorValues(t *testing.T) {
+    ctx := context.Background()
+    tooMany := make([]int, maxInValues+1)
+
+    for _, filter := range []Filter{
+        {Name: "Token", Op: In, Value: tooMany},
+        {Name: "Token", Op: In, Value: []string{}},
+        {Name: "Selectors", Op: Size, Value: 3},
+        {Name: "Selectors", Op: Size, Value: SizeComparison{Op: MatchAny, Value: 3}},
+        {Name: "Selectors", Op: AttributeType, Value: 3},
+    } {
+        mockClient := new(MockDynamoDBClient)
+        filters := []Filter{{Name: "NodeID", Op: EqualTo, Value: "node1"}, filter}
+        _, err := ListItems(ctx, "JoinTokensTable", mockClient, "NodeID", "", filters, nil, nil)
+        assert.Error(t, err, "%+v", filter)
+    }
+}
@@ -0,0 +1,55 @@
+package dynamodbstore
+
+import (
+    "context"
+    "fmt"
+    "iter"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ListItemsIter is the streaming form of ListItems: it fetches one Query page
+// at a time as the sequence is ranged over and yields the raw items, so
+// memory does not grow with the result set. Breaking out of the loop stops
+// the reads; the sequence ends after yielding an error.
+func ListItemsIter(
+    ctx context.Context,
+    kind string,
+    dynamoClient dynamoQueryClient,
+    partitionKey string,
+    sortKey string,
+    filters []Filter,
+    projection []string,
+    opts ...Option,
+) iter.Seq2[map[string]types.AttributeValue, error] {
+    return func(yield func(map[string]types.AttributeValue, error) bool) {
+        request, err := newQueryRequest(kind, partitionKey, sortKey, filters, projection, false, newOptions(opts))
+        if err != nil {
+            yield(nil, err)
+            return
+        }
+
+        queryPaginator := dynamodb.NewQueryPaginator(dynamoClient, request.input)
+        for queryPaginator.HasMorePages() {
+            page, err := queryPaginator.NextPage(ctx)
+            if err != nil {
+                yield(nil, fmt.Errorf("failed to fetch records: %w", err))
+                return
+            }
+
+            items, err := request.verifyPage(page.Items)
+            if err != nil {
+                yield(nil, err)
+                return
+            }
+            stripAttributes(items, request.verificationOnly)
+
+            for _, item := range items {
+                if !yield(item, nil) {
+                    return
+                }
+            }
+        }
+    }
+}
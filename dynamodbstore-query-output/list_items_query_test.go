@@ -29,7 +29,7 @@ func setupQueryTest(mockClient *MockDynamoDBClient, items []map[string]types.Att
     filters := []Filter{{Name: partitionKey, Op: EqualTo, Value: "test-partition-key"}}
     pagination := &Pagination{Limit: 10}
 
-    output, err := ListItems(ctx, tableName, mockClient, partitionKey, filters, pagination, projection)
+    output, err := ListItems(ctx, tableName, mockClient, partitionKey, "", filters, pagination, projection)
     assert.NoError(t, err)
     assert.NotNil(t, output)
 
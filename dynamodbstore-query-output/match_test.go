@@ -0,0 +1,134 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func selectorItem(spiffeID string, selectors ...string) map[string]types.AttributeValue {
+    return map[string]types.AttributeValue{
+        "SpiffeID":  &types.AttributeValueMemberS{Value: spiffeID},
+        "Selectors": &types.AttributeValueMemberSS{Value: selectors},
+    }
+}
+
+func TestListItemsMatchSubset(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{
+        Items: []map[string]types.AttributeValue{
+            selectorItem("spiffe://example.org/a", "unix:uid:0"),
+            selectorItem("spiffe://example.org/b", "unix:uid:0", "unix:gid:0"),
+            selectorItem("spiffe://example.org/c", "unix:uid:0", "k8s:ns:default"),
+        },
+        Count: 3,
+    }, nil)
+
+    filters := []Filter{
+        {Name: "ParentID", Op: EqualTo, Value: "spiffe://example.org/agent"},
+        {Name: "Selectors", Op: MatchSubset, Value: []string{"unix:uid:0", "unix:gid:0"}},
+    }
+    output, err := ListItems(ctx, "EntriesTable", mockClient, "ParentID", "", filters, nil, []string{"SpiffeID"})
+    assert.NoError(t, err)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Contains(t, *input.FilterExpression, "contains")
+
+    assert.Equal(t, int32(2), output.Count)
+    if assert.Len(t, output.Items, 2) {
+        assert.NotContains(t, output.Items[0], "Selectors")
+    }
+}
+
+func TestListItemsMatchSupersetAndAny(t *testing.T) {
+    ctx := context.Background()
+
+    for _, op := range []MatchBehavior{MatchSuperset, MatchAny} {
+        mockClient := new(MockDynamoDBClient)
+        mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+        filters := []Filter{
+            {Name: "ParentID", Op: EqualTo, Value: "spiffe://example.org/agent"},
+            {Name: "Selectors", Op: op, Value: []string{"unix:uid:0", "unix:gid:0"}},
+        }
+        _, err := ListItems(ctx, "EntriesTable", mockClient, "ParentID", "", filters, nil, nil)
+        assert.NoError(t, err)
+
+        input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+        assert.Len(t, input.ExpressionAttributeValues, 3)
+    }
+}
+
+func TestMatchFilterSetSemantics(t *testing.T) {
+    item := selectorItem("spiffe://example.org/a", "unix:uid:0", "unix:gid:0")
+
+    cases := []struct {
+        filter Filter
+        want   bool
+    }{
+        {Filter{Name: "Selectors", Op: MatchSuperset, Value: []string{"unix:uid:0"}}, true},
+        {Filter{Name: "Selectors", Op: MatchSuperset, Value: []string{"unix:uid:0", "k8s:ns:default"}}, false},
+        {Filter{Name: "Selectors", Op: MatchSubset, Value: []string{"unix:uid:0", "unix:gid:0", "k8s:ns:default"}}, true},
+        {Filter{Name: "Selectors", Op: MatchSubset, Value: []string{"unix:uid:0"}}, false},
+        {Filter{Name: "Selectors", Op: MatchAny, Value: []string{"k8s:ns:default", "unix:gid:0"}}, true},
+        {Filter{Name: "Selectors", Op: MatchAny, Value: 42}, false},
+        {Not(Filter{Name: "Selectors", Op: MatchSubset, Value: []string{"unix:uid:0"}}), true},
+        {Filter{Name: "Missing", Op: MatchAny, Value: []string{"unix:uid:0"}}, false},
+    }
+    for _, c := range cases {
+        matched, err := matchFilter(c.filter, item)
+        assert.NoError(t, err)
+        assert.Equal(t, c.want, matched, "%+v", c.filter)
+    }
+}
+
+func TestNegatedSubsetIsVerifiedLocally(t *testing.T) {
+    filters := []Filter{Not(Filter{Name: "Selectors", Op: MatchSubset, Value: []string{"unix:uid:0"}})}
+
+    _, hasCondition, verify, err := buildFilterExpression(filters)
+    assert.NoError(t, err)
+    assert.False(t, hasCondition)
+    assert.True(t, verify)
+}
+
+func TestMatchFilterExtendedOperators(t *testing.T) {
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    item := map[string]types.AttributeValue{
+        "Token":     &types.AttributeValueMemberS{Value: "token123"},
+        "ExpiresAt": &types.AttributeValueMemberS{Value: "2023-12-31T00:00:00Z"},
+        "Selectors": &types.AttributeValueMemberSS{Value: []string{"unix:uid:0", "unix:gid:0"}},
+        "Revision":  &types.AttributeValueMemberN{Value: "10"},
+    }
+
+    cases := []struct {
+        filter Filter
+        want   bool
+    }{
+        {Filter{Name: "ExpiresAt", Op: LessOrEqual, Value: now}, true},
+        {Filter{Name: "ExpiresAt", Op: GreaterOrEqual, Value: now}, false},
+        {Filter{Name: "Banned", Op: AttributeNotExists}, true},
+        {Filter{Name: "Banned", Op: AttributeExists}, false},
+        {Filter{Name: "Banned", Op: NotEqual, Value: true}, true},
+        {Filter{Name: "Token", Op: NotEqual, Value: "token123"}, false},
+        {Filter{Name: "Token", Op: In, Value: []string{"a", "token123"}}, true},
+        {Filter{Name: "Revision", Op: In, Value: []int{9, 11}}, false},
+        {Filter{Name: "Revision", Op: Between, Value: Range{Lower: 2, Upper: 10}}, true},
+        {Filter{Name: "Revision", Op: GreaterThan, Value: 9}, true},
+        {Filter{Name: "Selectors", Op: AttributeType, Value: expression.StringSet}, true},
+        {Filter{Name: "Token", Op: AttributeType, Value: "N"}, false},
+        {Filter{Name: "Selectors", Op: Size, Value: SizeComparison{Op: EqualTo, Value: 2}}, true},
+        {Filter{Name: "Token", Op: Size, Value: SizeComparison{Op: Between, Value: Range{Lower: 1, Upper: 5}}}, false},
+    }
+    for _, c := range cases {
+        matched, err := matchFilter(c.filter, item)
+        assert.NoError(t, err)
+        assert.Equal(t, c.want, matched, "%+v", c.filter)
+    }
+}
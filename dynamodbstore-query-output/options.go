@@ -0,0 +1,56 @@
+package dynamodbstore
+
+import (
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
+
+// Option adjusts how ListItems reads the table.
+type Option func(*options)
+
+type options struct {
+    direction SortDirection
+    expiry    string    // epoch attribute of ExcludeExpired
+    times     timeCodec // encodings of WithTimeEncoding
+}
+
+func newOptions(opts []Option) options {
+    var o options
+    for _, opt := range opts {
+        opt(&o)
+    }
+    return o
+}
+
+// SortDirection is the order in which a Query walks the sort key.
+type SortDirection int
+
+const (
+    Ascending SortDirection = iota
+    Descending
+)
+
+// WithSortDirection sets ScanIndexForward. Tokens carry the direction, so a
+// descending listing keeps paging towards older items and its tokens are not
+// accepted by an ascending one.
+func WithSortDirection(direction SortDirection) Option {
+    return func(o *options) {
+        o.direction = direction
+    }
+}
+
+// ExcludeExpired drops the items whose attribute, the Unix epoch seconds
+// DynamoDB Time to Live reads, is in the past; DynamoDB may keep returning
+// them for a while before deleting them. Items without it never expire.
+func ExcludeExpired(attribute string) Option {
+    return func(o *options) {
+        o.expiry = attribute
+    }
+}
+
+// unexpiredCondition holds for items without an expiry or expiring after now.
+func unexpiredCondition(attribute string, now time.Time) expression.ConditionBuilder {
+    epoch := expression.Name(attribute)
+    return expression.Or(epoch.AttributeNotExists(), epoch.GreaterThan(expression.Value(now.Unix())))
+}
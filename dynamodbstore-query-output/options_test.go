@@ -0,0 +1,81 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestListItemsSortDirection(t *testing.T) {
+    ctx := context.Background()
+    lastKey := map[string]types.AttributeValue{
+        "NodeID":    &types.AttributeValueMemberS{Value: "node1"},
+        "Timestamp": &types.AttributeValueMemberS{Value: "2024-01-02T00:00:00Z"},
+    }
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{LastEvaluatedKey: lastKey}, nil)
+
+    filters := []Filter{{Name: "NodeID", Op: EqualTo, Value: "node1"}}
+    _, err := ListItems(ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, nil, nil)
+    assert.NoError(t, err)
+    assert.Nil(t, mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput).ScanIndexForward)
+
+    pagination := &Pagination{Limit: 10}
+    _, err = ListItems(ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, pagination, nil, WithSortDirection(Descending))
+    assert.NoError(t, err)
+    input := mockClient.Calls[1].Arguments.Get(1).(*dynamodb.QueryInput)
+    if assert.NotNil(t, input.ScanIndexForward) {
+        assert.False(t, *input.ScanIndexForward)
+    }
+
+    // The next page keeps going backwards from the same key
+    next := &Pagination{Token: pagination.NextToken, Limit: 10}
+    _, err = ListItems(ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, next, nil, WithSortDirection(Descending))
+    assert.NoError(t, err)
+    assert.Equal(t, lastKey, mockClient.Calls[2].Arguments.Get(1).(*dynamodb.QueryInput).ExclusiveStartKey)
+
+    // but cannot be replayed in ascending order
+    var tokenErr *TokenError
+    _, err = ListItems(ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, &Pagination{Token: pagination.NextToken}, nil)
+    if assert.True(t, errors.As(err, &tokenErr), "%v", err) {
+        assert.Equal(t, TokenWrongQuery, tokenErr.Reason)
+    }
+}
+
+func TestListItemsExcludeExpired(t *testing.T) {
+    ctx := context.Background()
+    lastKey := map[string]types.AttributeValue{"Token": &types.AttributeValueMemberS{Value: "a"}}
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{LastEvaluatedKey: lastKey}, nil).Times(2)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Count: 3}, nil)
+
+    filters := []Filter{{Name: "Token", Op: EqualTo, Value: "a"}}
+    pagination := &Pagination{Limit: 1}
+    _, err := ListItems(ctx, "JoinTokensTable", mockClient, "Token", "", filters, pagination, nil, ExcludeExpired("ExpiresAtEpoch"))
+    if !assert.NoError(t, err) {
+        return
+    }
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Contains(t, *input.FilterExpression, "attribute_not_exists")
+    assert.Contains(t, attributeNames(input), "ExpiresAtEpoch")
+
+    // The next page is accepted although the clock moved on
+    next := &Pagination{Token: pagination.NextToken, Limit: 1}
+    _, err = ListItems(ctx, "JoinTokensTable", mockClient, "Token", "", filters, next, nil, ExcludeExpired("ExpiresAtEpoch"))
+    assert.NoError(t, err)
+
+    // Without the option the token belongs to another query
+    _, err = ListItems(ctx, "JoinTokensTable", mockClient, "Token", "", filters, &Pagination{Token: pagination.NextToken}, nil)
+    assert.ErrorIs(t, err, ErrInvalidToken)
+
+    // CountItems takes the same option
+    _, err = CountItems(ctx, "JoinTokensTable", mockClient, "Token", "", filters, ExcludeExpired("ExpiresAtEpoch"))
+    assert.NoError(t, err)
+    input = mockClient.Calls[2].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Contains(t, *input.FilterExpression, "attribute_not_exists")
+}
@@ -0,0 +1,113 @@
+package dynamodbstore
+
+import (
+    "fmt"
+    "time"
+)
+
+// Time encodings accepted by WithTimeEncoding. Attributes without one keep
+// the attributevalue encoding of time.Time.
+const (
+    timeRFC3339Nano = "rfc3339nano" // S, nanosecond precision in UTC
+    timeUnix        = "unix"        // N, seconds since the Unix epoch
+    timeUnixMilli   = "unixmilli"   // N, milliseconds since the Unix epoch
+)
+
+// Always nine fractional digits, unlike time.RFC3339Nano which trims
+// trailing zeros, so that the strings sort in time order.
+const sortableRFC3339Nano = "2006-01-02T15:04:05.000000000Z07:00"
+
+// timeCodec maps attributes to their time encoding. Items come back as raw
+// attribute maps, so only filter values are encoded.
+type timeCodec map[string]string
+
+// WithTimeEncoding encodes time.Time filter values on attribute the way the
+// stored items hold them: "rfc3339nano", "unix" or "unixmilli", the
+// encodings of the `dynamodbstore:"time=..."` tag in the generic store.
+// ListItems fails on any other encoding.
+func WithTimeEncoding(attribute, encoding string) Option {
+    return func(o *options) {
+        if o.times == nil {
+            o.times = make(timeCodec)
+        }
+        o.times[attribute] = encoding
+    }
+}
+
+func (c timeCodec) validate() error {
+    for attribute, encoding := range c {
+        switch encoding {
+        case timeRFC3339Nano, timeUnix, timeUnixMilli:
+        default:
+            return fmt.Errorf("unknown time encoding %q for %s", encoding, attribute)
+        }
+    }
+    return nil
+}
+
+// encode converts value to the encoding of attribute when it is a time; any
+// other value is returned unchanged.
+func (c timeCodec) encode(attribute string, value interface{}) interface{} {
+    encoding, ok := c[attribute]
+    if !ok {
+        return value
+    }
+
+    var t time.Time
+    switch v := value.(type) {
+    case time.Time:
+        t = v
+    case *time.Time:
+        if v == nil {
+            return value
+        }
+        t = *v
+    default:
+        return value
+    }
+
+    switch encoding {
+    case timeUnix:
+        return t.Unix()
+    case timeUnixMilli:
+        return t.UnixMilli()
+    }
+    return t.UTC().Format(sortableRFC3339Nano)
+}
+
+// encodeFilters returns filters with their time values encoded.
+func (c timeCodec) encodeFilters(filters []Filter) []Filter {
+    if len(c) == 0 || len(filters) == 0 {
+        return filters
+    }
+
+    encoded := make([]Filter, len(filters))
+    for i, filter := range filters {
+        if filter.Combinator != 0 {
+            filter.Filters = c.encodeFilters(filter.Filters)
+        } else if _, ok := c[filter.Name]; ok {
+            filter.Value = c.encodeFilterValue(filter)
+        }
+        encoded[i] = filter
+    }
+    return encoded
+}
+
+func (c timeCodec) encodeFilterValue(filter Filter) interface{} {
+    switch filter.Op {
+    case Between:
+        if bounds, ok := filter.Value.(Range); ok {
+            return Range{Lower: c.encode(filter.Name, bounds.Lower), Upper: c.encode(filter.Name, bounds.Upper)}
+        }
+    case In:
+        values, err := setValues(filter)
+        if err != nil {
+            return filter.Value
+        }
+        for i, value := range values {
+            values[i] = c.encode(filter.Name, value)
+        }
+        return values
+    }
+    return c.encode(filter.Name, filter.Value)
+}
@@ -0,0 +1,60 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestTimeCodecEncode(t *testing.T) {
+    at := time.Date(2024, 1, 1, 12, 0, 0, 500, time.FixedZone("BRT", -3*60*60))
+    assert.Equal(t, int64(1704121200), timeCodec{"T": timeUnix}.encode("T", at))
+    assert.Equal(t, int64(1704121200000), timeCodec{"T": timeUnixMilli}.encode("T", &at))
+    assert.Equal(t, "2024-01-01T15:00:00.000000500Z", timeCodec{"T": timeRFC3339Nano}.encode("T", at))
+    assert.Equal(t, "2024-01-01", timeCodec{"T": timeUnix}.encode("T", "2024-01-01"))
+    assert.Equal(t, at, timeCodec{"T": timeUnix}.encode("U", at))
+}
+
+func TestListItemsWithTimeEncoding(t *testing.T) {
+    ctx := context.Background()
+    at := time.UnixMilli(1704067200123).UTC()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+
+    filters := []Filter{
+        {Name: "NodeID", Op: EqualTo, Value: "node1"},
+        {Name: "Timestamp", Op: Between, Value: Range{Lower: at.Add(-time.Hour), Upper: at}},
+        Or(
+            Filter{Name: "SeenAt", Op: In, Value: []time.Time{at}},
+            Filter{Name: "SeenAt", Op: AttributeNotExists},
+        ),
+    }
+    _, err := ListItems(ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, nil, nil,
+        WithTimeEncoding("Timestamp", "unixmilli"), WithTimeEncoding("SeenAt", "unix"))
+    if !assert.NoError(t, err) {
+        return
+    }
+
+    // The key condition and the filter compare against the stored numbers
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.QueryInput)
+    var numbers []string
+    for _, value := range input.ExpressionAttributeValues {
+        if n, ok := value.(*types.AttributeValueMemberN); ok {
+            numbers = append(numbers, n.Value)
+        }
+    }
+    assert.ElementsMatch(t, []string{"1704063600123", "1704067200123", "1704067200"}, numbers)
+    assert.Contains(t, *input.KeyConditionExpression, "BETWEEN")
+
+    _, err = ListItems(ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, nil, nil,
+        WithTimeEncoding("Timestamp", "iso"))
+    assert.Error(t, err)
+    _, err = CountItems(ctx, "NodeEventsTable", mockClient, "NodeID", "Timestamp", filters, WithTimeEncoding("Timestamp", "iso"))
+    assert.Error(t, err)
+    mockClient.AssertNumberOfCalls(t, "Query", 1)
+}
@@ -0,0 +1,330 @@
+package dynamodbstore
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "hash"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrInvalidToken matches every error returned for a Pagination.Token that
+// cannot be used; use errors.As with *TokenError to learn why.
+var ErrInvalidToken = errors.New("invalid pagination token")
+
+// TokenErrorReason tells why a pagination token was rejected.
+type TokenErrorReason int
+
+const (
+    TokenMalformed TokenErrorReason = iota + 1
+    TokenBadSignature
+    TokenExpired
+    TokenWrongTable
+    TokenWrongQuery
+)
+
+func (r TokenErrorReason) String() string {
+    switch r {
+    case TokenMalformed:
+        return "malformed"
+    case TokenBadSignature:
+        return "bad signature"
+    case TokenExpired:
+        return "expired"
+    case TokenWrongTable:
+        return "issued for a different table"
+    case TokenWrongQuery:
+        return "issued for a different query (filters, key condition or projection changed)"
+    }
+    return fmt.Sprintf("reason %d", int(r))
+}
+
+// TokenError is returned when Pagination.Token is rejected.
+type TokenError struct {
+    Reason TokenErrorReason
+    Err    error
+}
+
+func (e *TokenError) Error() string {
+    if e.Err != nil {
+        return fmt.Sprintf("%v: %v: %v", ErrInvalidToken, e.Reason, e.Err)
+    }
+    return fmt.Sprintf("%v: %v", ErrInvalidToken, e.Reason)
+}
+
+func (e *TokenError) Unwrap() error { return e.Err }
+
+func (e *TokenError) Is(target error) bool { return target == ErrInvalidToken }
+
+// SigningKey is one HMAC secret of a TokenSigner, identified by ID so tokens
+// signed before a rotation can still be verified.
+type SigningKey struct {
+    ID     string
+    Secret []byte
+}
+
+// TokenSigner authenticates pagination tokens with HMAC-SHA256. Keys[0]
+// signs new tokens and every key in Keys is accepted when verifying, so a
+// key is rotated by prepending the new one and dropping the old one once its
+// tokens have expired. A zero TTL issues tokens that never expire.
+type TokenSigner struct {
+    Keys []SigningKey
+    TTL  time.Duration
+
+    now func() time.Time
+}
+
+func (s *TokenSigner) clock() time.Time {
+    if s.now != nil {
+        return s.now()
+    }
+    return time.Now()
+}
+
+func (s *TokenSigner) key(id string) []byte {
+    for _, key := range s.Keys {
+        if key.ID == id {
+            return key.Secret
+        }
+    }
+    return nil
+}
+
+func (s *TokenSigner) mac(secret []byte, payload string) string {
+    h := hmac.New(sha256.New, secret)
+    h.Write([]byte(payload))
+    return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// tokenVersion is bumped whenever the token layout changes so tokens from an
+// older release are rejected instead of misread.
+const tokenVersion = 1
+
+// pageToken is the JSON layout behind Pagination.NextToken. It carries every
+// attribute of LastEvaluatedKey, so tables with sort keys and index queries
+// resume at the right position. Signed tokens are followed by "." and the
+// MAC of the encoded payload.
+type pageToken struct {
+    Version int                       `json:"v"`
+    Table   string                    `json:"t"`
+    Query   string                    `json:"q"`
+    Key     map[string]tokenAttribute `json:"k"`
+    KeyID   string                    `json:"kid,omitempty"`
+    Expires int64                     `json:"exp,omitempty"`
+}
+
+// tokenAttribute holds one key attribute; keys can only be S, N or B.
+type tokenAttribute struct {
+    S *string `json:"s,omitempty"`
+    N *string `json:"n,omitempty"`
+    B []byte  `json:"b,omitempty"`
+}
+
+func encodeToken(table, fingerprint string, key map[string]types.AttributeValue, signer *TokenSigner) (string, error) {
+    token := pageToken{Version: tokenVersion, Table: table, Query: fingerprint, Key: make(map[string]tokenAttribute, len(key))}
+    for name, value := range key {
+        switch v := value.(type) {
+        case *types.AttributeValueMemberS:
+            token.Key[name] = tokenAttribute{S: &v.Value}
+        case *types.AttributeValueMemberN:
+            token.Key[name] = tokenAttribute{N: &v.Value}
+        case *types.AttributeValueMemberB:
+            token.Key[name] = tokenAttribute{B: v.Value}
+        default:
+            return "", fmt.Errorf("unsupported key attribute %q of type %T", name, value)
+        }
+    }
+
+    if signer != nil {
+        if len(signer.Keys) == 0 {
+            return "", errors.New("token signer has no keys")
+        }
+        token.KeyID = signer.Keys[0].ID
+        if signer.TTL > 0 {
+            token.Expires = signer.clock().Add(signer.TTL).Unix()
+        }
+    }
+
+    data, err := json.Marshal(token)
+    if err != nil {
+        return "", err
+    }
+    payload := base64.RawURLEncoding.EncodeToString(data)
+    if signer == nil {
+        return payload, nil
+    }
+    return payload + "." + signer.mac(signer.Keys[0].Secret, payload), nil
+}
+
+func decodeToken(table, fingerprint, encoded string, signer *TokenSigner) (map[string]types.AttributeValue, error) {
+    payload, signature, signed := strings.Cut(encoded, ".")
+
+    data, err := base64.RawURLEncoding.DecodeString(payload)
+    if err != nil {
+        return nil, &TokenError{Reason: TokenMalformed, Err: err}
+    }
+
+    var token pageToken
+    if err := json.Unmarshal(data, &token); err != nil {
+        return nil, &TokenError{Reason: TokenMalformed, Err: err}
+    }
+    if token.Version != tokenVersion {
+        return nil, &TokenError{Reason: TokenMalformed, Err: fmt.Errorf("unsupported version %d", token.Version)}
+    }
+
+    // Nothing in the payload is trusted until the signature is checked.
+    if signer != nil {
+        secret := signer.key(token.KeyID)
+        if !signed || secret == nil || !hmac.Equal([]byte(signature), []byte(signer.mac(secret, payload))) {
+            return nil, &TokenError{Reason: TokenBadSignature}
+        }
+        if token.Expires != 0 && !signer.clock().Before(time.Unix(token.Expires, 0)) {
+            return nil, &TokenError{Reason: TokenExpired}
+        }
+    }
+
+    if token.Table != table {
+        return nil, &TokenError{Reason: TokenWrongTable, Err: fmt.Errorf("token is for %q, not %q", token.Table, table)}
+    }
+    if token.Query != fingerprint {
+        return nil, &TokenError{Reason: TokenWrongQuery}
+    }
+    if len(token.Key) == 0 {
+        return nil, &TokenError{Reason: TokenMalformed, Err: errors.New("empty key")}
+    }
+
+    key := make(map[string]types.AttributeValue, len(token.Key))
+    for name, value := range token.Key {
+        switch {
+        case value.S != nil && value.N == nil && value.B == nil:
+            key[name] = &types.AttributeValueMemberS{Value: *value.S}
+        case value.N != nil && value.S == nil && value.B == nil:
+            key[name] = &types.AttributeValueMemberN{Value: *value.N}
+        case value.B != nil && value.S == nil && value.N == nil:
+            key[name] = &types.AttributeValueMemberB{Value: value.B}
+        default:
+            return nil, &TokenError{Reason: TokenMalformed, Err: fmt.Errorf("malformed attribute %q", name)}
+        }
+    }
+    return key, nil
+}
+
+// itemKey picks from item the attributes named by a LastEvaluatedKey, so a
+// page cut short at item resumes right after it.
+func itemKey(item, lastEvaluatedKey map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+    if len(lastEvaluatedKey) == 0 {
+        return nil, errors.New("no known key to resume after the last item")
+    }
+    key := make(map[string]types.AttributeValue, len(lastEvaluatedKey))
+    for name := range lastEvaluatedKey {
+        value, ok := item[name]
+        if !ok {
+            return nil, fmt.Errorf("key attribute %q is missing from the item; include it in the projection", name)
+        }
+        key[name] = value
+    }
+    return key, nil
+}
+
+// queryFingerprint hashes everything that decides which items a request
+// returns and in which order, so a token is only accepted by the query that
+// issued it.
+func queryFingerprint(table string, expr expression.Expression, settings options) string {
+    h := sha256.New()
+    writeString(h, table)
+    writeString(h, fmt.Sprint(int(settings.direction)))
+    if settings.expiry != "" {
+        writeString(h, "unexpired:"+settings.expiry)
+    }
+    for _, part := range []*string{expr.KeyCondition(), expr.Filter(), expr.Projection()} {
+        if part == nil {
+            writeString(h, "")
+            continue
+        }
+        writeString(h, *part)
+    }
+
+    names := expr.Names()
+    for _, placeholder := range sortedKeys(names) {
+        writeString(h, placeholder)
+        writeString(h, names[placeholder])
+    }
+    values := expr.Values()
+    for _, placeholder := range sortedKeys(values) {
+        writeString(h, placeholder)
+        writeValue(h, values[placeholder])
+    }
+    return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16])
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+// writeString is length prefixed so adjacent fields cannot run together.
+func writeString(h hash.Hash, s string) {
+    fmt.Fprintf(h, "%d:%s", len(s), s)
+}
+
+func writeValue(h hash.Hash, value types.AttributeValue) {
+    switch v := value.(type) {
+    case *types.AttributeValueMemberS:
+        writeString(h, "S")
+        writeString(h, v.Value)
+    case *types.AttributeValueMemberN:
+        writeString(h, "N")
+        writeString(h, v.Value)
+    case *types.AttributeValueMemberB:
+        writeString(h, "B")
+        writeString(h, string(v.Value))
+    case *types.AttributeValueMemberBOOL:
+        writeString(h, "BOOL")
+        writeString(h, fmt.Sprint(v.Value))
+    case *types.AttributeValueMemberNULL:
+        writeString(h, "NULL")
+    case *types.AttributeValueMemberSS:
+        writeString(h, "SS")
+        writeStrings(h, v.Value)
+    case *types.AttributeValueMemberNS:
+        writeString(h, "NS")
+        writeStrings(h, v.Value)
+    case *types.AttributeValueMemberBS:
+        writeString(h, "BS")
+        for _, b := range v.Value {
+            writeString(h, string(b))
+        }
+    case *types.AttributeValueMemberL:
+        writeString(h, fmt.Sprintf("L%d", len(v.Value)))
+        for _, item := range v.Value {
+            writeValue(h, item)
+        }
+    case *types.AttributeValueMemberM:
+        writeString(h, fmt.Sprintf("M%d", len(v.Value)))
+        for _, name := range sortedKeys(v.Value) {
+            writeString(h, name)
+            writeValue(h, v.Value[name])
+        }
+    default:
+        writeString(h, fmt.Sprintf("%T", value))
+    }
+}
+
+func writeStrings(h hash.Hash, values []string) {
+    writeString(h, fmt.Sprint(len(values)))
+    for _, value := range values {
+        writeString(h, value)
+    }
+}
@@ -0,0 +1,204 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestTokenRoundTrip(t *testing.T) {
+    key := map[string]types.AttributeValue{
+        "TrustDomain": &types.AttributeValueMemberS{Value: "example.org"},
+        "EventID":     &types.AttributeValueMemberN{Value: "42"},
+        "Digest":      &types.AttributeValueMemberB{Value: []byte{0x00, 0xff, 0x10}},
+    }
+
+    token, err := encodeToken("EntriesTable", "", key, nil)
+    assert.NoError(t, err)
+
+    decoded, err := decodeToken("EntriesTable", "", token, nil)
+    assert.NoError(t, err)
+    assert.Equal(t, key, decoded)
+}
+
+func TestDecodeTokenRejectsInvalidTokens(t *testing.T) {
+    for _, token := range []string{
+        "not base64!",
+        "bm90IGpzb24",                                          // "not json"
+        "eyJ2IjoyLCJrIjp7IkEiOnsicyI6ImEifX19",                 // version 2
+        "eyJ2IjoxLCJ0IjoiRW50cmllc1RhYmxlIiwiayI6e319",         // empty key
+        "eyJ2IjoxLCJ0IjoiRW50cmllc1RhYmxlIiwiayI6eyJBIjp7fX19", // attribute without a member
+    } {
+        _, err := decodeToken("EntriesTable", "", token, nil)
+        assert.ErrorIs(t, err, ErrInvalidToken, token)
+    }
+}
+
+func TestEncodeTokenRejectsNonKeyAttributes(t *testing.T) {
+    _, err := encodeToken("EntriesTable", "", map[string]types.AttributeValue{
+        "Selectors": &types.AttributeValueMemberSS{Value: []string{"a"}},
+    }, nil)
+    assert.Error(t, err)
+}
+
+func TestListItemsResumesFromFullKey(t *testing.T) {
+    ctx := context.Background()
+    lastKey := map[string]types.AttributeValue{
+        "TrustDomain": &types.AttributeValueMemberS{Value: "example.org"},
+        "EventID":     &types.AttributeValueMemberN{Value: "7"},
+        "GSI1PK":      &types.AttributeValueMemberS{Value: "BUNDLE"},
+    }
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{LastEvaluatedKey: lastKey}, nil).Once()
+
+    filters := []Filter{{Name: "TrustDomain", Op: EqualTo, Value: "example.org"}}
+    pagination := &Pagination{Limit: 10}
+    _, err := ListItems(ctx, "FederationRelationshipsTable", mockClient, "TrustDomain", "EventID", filters, pagination, nil)
+    assert.NoError(t, err)
+    assert.NotEmpty(t, pagination.NextToken)
+
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+    pagination.Token = pagination.NextToken
+    _, err = ListItems(ctx, "FederationRelationshipsTable", mockClient, "TrustDomain", "EventID", filters, pagination, nil)
+    assert.NoError(t, err)
+    assert.Empty(t, pagination.NextToken)
+
+    input := mockClient.Calls[1].Arguments.Get(1).(*dynamodb.QueryInput)
+    assert.Equal(t, lastKey, input.ExclusiveStartKey)
+}
+
+func TestListItemsRejectsInvalidToken(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+
+    filters := []Filter{{Name: "TrustDomain", Op: EqualTo, Value: "example.org"}}
+    _, err := ListItems(ctx, "FederationRelationshipsTable", mockClient, "TrustDomain", "", filters, &Pagination{Token: "example.org"}, nil)
+    assert.ErrorIs(t, err, ErrInvalidToken)
+    mockClient.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+}
+
+func TestSignedTokens(t *testing.T) {
+    key := map[string]types.AttributeValue{"SpiffeID": &types.AttributeValueMemberS{Value: "spiffe://example.org/node"}}
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    signer := &TokenSigner{
+        Keys: []SigningKey{{ID: "k1", Secret: []byte("first secret")}},
+        TTL:  time.Hour,
+        now:  func() time.Time { return now },
+    }
+
+    token, err := encodeToken("EntriesTable", "", key, signer)
+    assert.NoError(t, err)
+
+    decoded, err := decodeToken("EntriesTable", "", token, signer)
+    assert.NoError(t, err)
+    assert.Equal(t, key, decoded)
+
+    unsigned, err := encodeToken("EntriesTable", "", key, nil)
+    assert.NoError(t, err)
+    forged, err := encodeToken("EntriesTable", "", key, &TokenSigner{Keys: []SigningKey{{ID: "k1", Secret: []byte("guessed")}}})
+    assert.NoError(t, err)
+    other, err := encodeToken("BundlesTable", "", key, signer)
+    assert.NoError(t, err)
+
+    // Swaps the payload of a valid token for another key, keeping the MAC
+    moved, err := encodeToken("EntriesTable", "", map[string]types.AttributeValue{"SpiffeID": &types.AttributeValueMemberS{Value: "spiffe://example.org/other"}}, signer)
+    assert.NoError(t, err)
+    payload, _, _ := strings.Cut(moved, ".")
+    _, signature, _ := strings.Cut(token, ".")
+    tampered := payload + "." + signature
+
+    rotated := &TokenSigner{
+        Keys: []SigningKey{{ID: "k2", Secret: []byte("second secret")}, signer.Keys[0]},
+        TTL:  time.Hour,
+        now:  signer.now,
+    }
+    decoded, err = decodeToken("EntriesTable", "", token, rotated)
+    assert.NoError(t, err)
+    assert.Equal(t, key, decoded)
+
+    retired := &TokenSigner{Keys: rotated.Keys[:1], now: signer.now}
+    expired := &TokenSigner{Keys: signer.Keys, now: func() time.Time { return now.Add(2 * time.Hour) }}
+
+    cases := []struct {
+        token  string
+        signer *TokenSigner
+        reason TokenErrorReason
+    }{
+        {unsigned, signer, TokenBadSignature},
+        {forged, signer, TokenBadSignature},
+        {tampered, signer, TokenBadSignature},
+        {token, retired, TokenBadSignature},
+        {token, expired, TokenExpired},
+        {other, signer, TokenWrongTable},
+    }
+    for _, c := range cases {
+        _, err := decodeToken("EntriesTable", "", c.token, c.signer)
+        var tokenErr *TokenError
+        if assert.True(t, errors.As(err, &tokenErr), "%v", err) {
+            assert.Equal(t, c.reason, tokenErr.Reason)
+        }
+        assert.ErrorIs(t, err, ErrInvalidToken)
+    }
+}
+
+func TestListItemsRejectsTokenFromAnotherQuery(t *testing.T) {
+    ctx := context.Background()
+    lastKey := map[string]types.AttributeValue{"TrustDomain": &types.AttributeValueMemberS{Value: "example.org"}}
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{LastEvaluatedKey: lastKey}, nil)
+
+    filters := []Filter{{Name: "TrustDomain", Op: EqualTo, Value: "example.org"}}
+    pagination := &Pagination{Limit: 10}
+    _, err := ListItems(ctx, "FederationRelationshipsTable", mockClient, "TrustDomain", "", filters, pagination, nil)
+    assert.NoError(t, err)
+
+    var tokenErr *TokenError
+    other := []Filter{{Name: "TrustDomain", Op: EqualTo, Value: "other.org"}}
+    _, err = ListItems(ctx, "FederationRelationshipsTable", mockClient, "TrustDomain", "", other, &Pagination{Token: pagination.NextToken}, nil)
+    if assert.True(t, errors.As(err, &tokenErr), "%v", err) {
+        assert.Equal(t, TokenWrongQuery, tokenErr.Reason)
+    }
+
+    _, err = ListItems(ctx, "FederationRelationshipsTable", mockClient, "TrustDomain", "", filters, &Pagination{Token: pagination.NextToken}, nil)
+    assert.NoError(t, err)
+}
+
+func TestListItemsFillsPageToLimit(t *testing.T) {
+    ctx := context.Background()
+    event := func(id string) map[string]types.AttributeValue {
+        return map[string]types.AttributeValue{
+            "TrustDomain": &types.AttributeValueMemberS{Value: "example.org"},
+            "EventID":     &types.AttributeValueMemberN{Value: id},
+        }
+    }
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{event("1")}, Count: 1, ScannedCount: 2, LastEvaluatedKey: event("2")}, nil).Once()
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{event("3"), event("4"), event("5")}, Count: 3, ScannedCount: 3, LastEvaluatedKey: event("5")}, nil).Once()
+
+    filters := []Filter{{Name: "TrustDomain", Op: EqualTo, Value: "example.org"}}
+    pagination := &Pagination{Limit: 3, Fill: true}
+    output, err := ListItems(ctx, "EntryEventsTable", mockClient, "TrustDomain", "EventID", filters, pagination, nil)
+    assert.NoError(t, err)
+    assert.Equal(t, []map[string]types.AttributeValue{event("1"), event("3"), event("4")}, output.Items)
+    assert.Equal(t, int32(3), output.Count)
+    assert.Equal(t, int32(5), output.ScannedCount)
+    assert.Equal(t, event("4"), output.LastEvaluatedKey)
+    assert.Equal(t, event("2"), mockClient.Calls[1].Arguments.Get(1).(*dynamodb.QueryInput).ExclusiveStartKey)
+
+    mockClient.On("Query", ctx, mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+    pagination.Token = pagination.NextToken
+    _, err = ListItems(ctx, "EntryEventsTable", mockClient, "TrustDomain", "EventID", filters, pagination, nil)
+    assert.NoError(t, err)
+    assert.Equal(t, event("4"), mockClient.Calls[2].Arguments.Get(1).(*dynamodb.QueryInput).ExclusiveStartKey)
+    assert.Empty(t, pagination.NextToken)
+}
@@ -0,0 +1,168 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// Workers de um ScanRunner que não define Workers
+const defaultWorkers = 16
+
+// Guarda a posição de um scan para que ele possa ser retomado. Load retorna
+// "" quando não há checkpoint com esse nome.
+type CheckpointStore interface {
+    Load(ctx context.Context, name string) (string, error)
+    Save(ctx context.Context, name, token string) error
+    Delete(ctx context.Context, name string) error
+}
+
+// Scan completo da tabela, em paralelo por segmentos, que grava a posição de
+// cada segmento em Store e continua dela quando Run é chamado de novo com o
+// mesmo Name. Os itens lidos depois do último checkpoint são entregues de
+// novo na retomada, então handle deve tolerar repetições.
+type ScanRunner[T any] struct {
+    Name       string // Identifica o checkpoint
+    TableName  string
+    Client     DynamoDBAPI
+    Filters    []Filter
+    Projection []string
+    Segments   int // Segmentos em que a tabela é dividida (padrão 1)
+    Workers    int // Segmentos lidos ao mesmo tempo (padrão Segments, até 16)
+    PageSize   int // Limit de cada página
+    Store      CheckpointStore
+    Interval   time.Duration // Intervalo mínimo entre gravações; zero grava a cada rodada
+    Signer     *TokenSigner  // Assina o checkpoint (opcional)
+}
+
+// Percorre a tabela chamando handle para cada item. O checkpoint é removido
+// quando a tabela termina; se Run parar antes, por erro ou cancelamento do
+// contexto, a última posição já entregue a handle é gravada.
+func (r *ScanRunner[T]) Run(ctx context.Context, handle func(T) error) error {
+    segments := r.Segments
+    if segments == 0 {
+        segments = 1
+    }
+    workers := r.Workers
+    if workers == 0 {
+        workers = min(segments, defaultWorkers)
+    }
+
+    token, err := r.Store.Load(ctx, r.Name)
+    if err != nil {
+        return fmt.Errorf("falha ao carregar checkpoint %q: %w", r.Name, err)
+    }
+
+    saved, lastSave := token, time.Now()
+    save := func(ctx context.Context) error {
+        if token == saved {
+            return nil
+        }
+        if err := r.Store.Save(ctx, r.Name, token); err != nil {
+            return fmt.Errorf("falha ao gravar checkpoint %q: %w", r.Name, err)
+        }
+        saved, lastSave = token, time.Now()
+        return nil
+    }
+    // Grava o progresso mesmo com o contexto cancelado
+    fail := func(err error) error {
+        return errors.Join(err, save(context.WithoutCancel(ctx)))
+    }
+
+    for {
+        pagination := &Pagination{Token: token, Limit: r.PageSize, Signer: r.Signer}
+        items, pagination, err := ListItemsParallel[T](ctx, r.TableName, r.Client, r.Filters, pagination, r.Projection, segments, workers)
+        if err != nil {
+            return fail(err)
+        }
+        for _, item := range items {
+            if err := handle(item); err != nil {
+                return fail(err)
+            }
+        }
+
+        token = pagination.NextToken
+        if token == "" {
+            if err := r.Store.Delete(ctx, r.Name); err != nil {
+                return fmt.Errorf("falha ao remover checkpoint %q: %w", r.Name, err)
+            }
+            return nil
+        }
+        if time.Since(lastSave) >= r.Interval {
+            if err := save(ctx); err != nil {
+                return err
+            }
+        }
+    }
+}
+
+// CheckpointStore em arquivos locais: cada checkpoint fica em
+// <Dir>/<name>.checkpoint, gravado de forma atômica.
+type FileCheckpointStore struct {
+    Dir string
+}
+
+func (s FileCheckpointStore) path(name string) (string, error) {
+    if name == "" || filepath.Base(name) != name {
+        return "", fmt.Errorf("nome de checkpoint inválido: %q", name)
+    }
+    return filepath.Join(s.Dir, name+".checkpoint"), nil
+}
+
+func (s FileCheckpointStore) Load(ctx context.Context, name string) (string, error) {
+    path, err := s.path(name)
+    if err != nil {
+        return "", err
+    }
+    data, err := os.ReadFile(path)
+    if errors.Is(err, fs.ErrNotExist) {
+        return "", nil
+    }
+    return string(data), err
+}
+
+func (s FileCheckpointStore) Save(ctx context.Context, name, token string) error {
+    path, err := s.path(name)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+        return err
+    }
+
+    // Grava em um arquivo temporário e renomeia, para nunca deixar um
+    // checkpoint pela metade
+    tmp, err := os.CreateTemp(s.Dir, name+".*.tmp")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    if _, err := tmp.WriteString(token); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Sync(); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
+
+func (s FileCheckpointStore) Delete(ctx context.Context, name string) error {
+    path, err := s.path(name)
+    if err != nil {
+        return err
+    }
+    if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+        return err
+    }
+    return nil
+}
@@ -0,0 +1,123 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestScanRunnerResumesFromCheckpoint(t *testing.T) {
+    ctx := context.Background()
+    store := FileCheckpointStore{Dir: t.TempDir()}
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", mock.Anything, mock.Anything).Return(bundlePage("a", "a"), nil).Once()
+    mockClient.On("Scan", mock.Anything, mock.Anything).Return(bundlePage("b", "b"), nil).Once()
+
+    runner := &ScanRunner[Bundle]{Name: "bundles", TableName: "BundlesTable", Client: mockClient, Segments: 1, PageSize: 1, Store: store}
+
+    // Falha no segundo item: a posição gravada é a do primeiro
+    failure := errors.New("falha no processamento")
+    var ids []string
+    err := runner.Run(ctx, func(bundle Bundle) error {
+        if bundle.ID == "b" {
+            return failure
+        }
+        ids = append(ids, bundle.ID)
+        return nil
+    })
+    if !assert.ErrorIs(t, err, failure) {
+        return
+    }
+
+    token, err := store.Load(ctx, "bundles")
+    assert.NoError(t, err)
+    assert.NotEmpty(t, token)
+
+    mockClient.On("Scan", mock.Anything, mock.Anything).Return(bundlePage("b", "b"), nil).Once()
+    mockClient.On("Scan", mock.Anything, mock.Anything).Return(bundlePage("", "c"), nil).Once()
+
+    err = runner.Run(ctx, func(bundle Bundle) error {
+        ids = append(ids, bundle.ID)
+        return nil
+    })
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Equal(t, []string{"a", "b", "c"}, ids)
+    if !mockClient.AssertExpectations(t) {
+        return
+    }
+
+    input := mockClient.Calls[2].Arguments.Get(1).(*dynamodb.ScanInput)
+    assert.Equal(t, bundleItem("a"), input.ExclusiveStartKey)
+
+    // Terminado o scan o checkpoint é removido
+    token, err = store.Load(ctx, "bundles")
+    assert.NoError(t, err)
+    assert.Empty(t, token)
+}
+
+func TestScanRunnerRejectsCheckpointForOtherQuery(t *testing.T) {
+    ctx := context.Background()
+    store := FileCheckpointStore{Dir: t.TempDir()}
+    mockClient := new(MockDynamoDBClient)
+
+    request, err := newScanRequest("BundlesTable", nil, nil, "")
+    assert.NoError(t, err)
+    token, err := encodeSegmentsToken("BundlesTable", request.fingerprint, []segmentPosition{{key: bundleItem("a")}}, nil)
+    assert.NoError(t, err)
+    assert.NoError(t, store.Save(ctx, "bundles", token))
+
+    runner := &ScanRunner[Bundle]{
+        Name:      "bundles",
+        TableName: "BundlesTable",
+        Client:    mockClient,
+        Filters:   []Filter{{Name: "ID", Op: EqualTo, Value: "a"}},
+        Store:     store,
+    }
+    err = runner.Run(ctx, func(Bundle) error { return nil })
+    assert.ErrorIs(t, err, ErrInvalidToken)
+    mockClient.AssertNotCalled(t, "Scan", mock.Anything, mock.Anything)
+
+    // O checkpoint rejeitado continua lá
+    saved, err := store.Load(ctx, "bundles")
+    assert.NoError(t, err)
+    assert.Equal(t, token, saved)
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+    ctx := context.Background()
+    dir := filepath.Join(t.TempDir(), "checkpoints")
+    store := FileCheckpointStore{Dir: dir}
+
+    token, err := store.Load(ctx, "bundles")
+    assert.NoError(t, err)
+    assert.Empty(t, token)
+
+    assert.NoError(t, store.Save(ctx, "bundles", "primeiro"))
+    assert.NoError(t, store.Save(ctx, "bundles", "segundo"))
+    token, err = store.Load(ctx, "bundles")
+    assert.NoError(t, err)
+    assert.Equal(t, "segundo", token)
+
+    // Nenhum arquivo temporário fica para trás
+    entries, err := os.ReadDir(dir)
+    assert.NoError(t, err)
+    assert.Len(t, entries, 1)
+
+    assert.NoError(t, store.Delete(ctx, "bundles"))
+    assert.NoError(t, store.Delete(ctx, "bundles"))
+    token, err = store.Load(ctx, "bundles")
+    assert.NoError(t, err)
+    assert.Empty(t, token)
+
+    for _, name := range []string{"", "../bundles", "a/b"} {
+        assert.Error(t, store.Save(ctx, name, "token"), name)
+    }
+}
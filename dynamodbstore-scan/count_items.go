@@ -0,0 +1,79 @@
+package dynamodbstore
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Resultado de CountItems
+type ItemCount struct {
+    Count        int64 // Itens que satisfazem os filtros
+    ScannedCount int64 // Itens lidos antes da expressão de filtro
+}
+
+// Conta os itens que ListItems retornaria para os mesmos filtros, seguindo
+// todas as páginas sem deserializá-las. Usa Select COUNT; quando algum filtro
+// precisa de verificação local, lê apenas os atributos usados pelos filtros
+// e conta os itens que passam.
+func CountItems(ctx context.Context, tableName string, dynamoClient DynamoDBAPI, filters []Filter) (ItemCount, error) {
+    request, err := newScanRequest(tableName, filters, nil, "")
+    if err != nil {
+        return ItemCount{}, err
+    }
+    if request.verify {
+        if request, err = newScanRequest(tableName, filters, countProjection(filters), ""); err != nil {
+            return ItemCount{}, err
+        }
+    } else {
+        request.input.Select = types.SelectCount
+    }
+
+    var count ItemCount
+    scanPaginator := dynamodb.NewScanPaginator(dynamoClient, request.input)
+    for scanPaginator.HasMorePages() {
+        page, err := scanPaginator.NextPage(ctx)
+        if err != nil {
+            return ItemCount{}, fmt.Errorf("falha ao contar registros: %w", err)
+        }
+        count.ScannedCount += int64(page.ScannedCount)
+
+        if !request.verify {
+            count.Count += int64(page.Count)
+            continue
+        }
+        items, err := request.verifyPage(page.Items)
+        if err != nil {
+            return ItemCount{}, err
+        }
+        count.Count += int64(len(items))
+    }
+    return count, nil
+}
+
+// CountItems para itens do tipo T: os valores de tempo dos filtros usam as
+// codificações dos campos de T
+func CountItemsOf[T any](ctx context.Context, tableName string, dynamoClient DynamoDBAPI, filters []Filter) (ItemCount, error) {
+    times, err := timeCodecFor[T]()
+    if err != nil {
+        return ItemCount{}, err
+    }
+    return CountItems(ctx, tableName, dynamoClient, times.encodeFilters(filters))
+}
+
+// Atributos de primeiro nível lidos pelos filtros
+func countProjection(filters []Filter) []string {
+    seen := make(map[string]bool)
+    var names []string
+    for _, name := range filterNames(And(filters...)) {
+        name = strings.Split(name, ".")[0]
+        if !seen[name] {
+            seen[name] = true
+            names = append(names, name)
+        }
+    }
+    return names
+}
@@ -0,0 +1,69 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestCountItemsUsesSelectCount(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{Count: 2, ScannedCount: 10, LastEvaluatedKey: bundleItem("a")}, nil).Once()
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{Count: 3, ScannedCount: 4}, nil).Once()
+
+    filters := []Filter{{Name: "ExpiresAt", Op: LessThan, Value: "2024-01-01T00:00:00Z"}}
+    count, err := CountItems(ctx, "JoinTokensTable", mockClient, filters)
+    assert.NoError(t, err)
+    assert.Equal(t, ItemCount{Count: 5, ScannedCount: 14}, count)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput)
+    assert.Equal(t, types.SelectCount, input.Select)
+    assert.Nil(t, input.ProjectionExpression)
+    assert.NotNil(t, input.FilterExpression)
+}
+
+func TestCountItemsWithoutFilters(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{Count: 7, ScannedCount: 7, LastEvaluatedKey: bundleItem("g")}, nil).Once()
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{Count: 2, ScannedCount: 2}, nil).Once()
+
+    count, err := CountItems(ctx, "BundlesTable", mockClient, nil)
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Equal(t, ItemCount{Count: 9, ScannedCount: 9}, count)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput)
+    assert.Equal(t, types.SelectCount, input.Select)
+    assert.Nil(t, input.FilterExpression)
+    assert.Nil(t, input.ProjectionExpression)
+    assert.Equal(t, bundleItem("g"), mockClient.Calls[1].Arguments.Get(1).(*dynamodb.ScanInput).ExclusiveStartKey)
+}
+
+func TestCountItemsVerifiesLocally(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{
+        Items: []map[string]types.AttributeValue{
+            selectorItem("spiffe://example.org/a", "unix:uid:0"),
+            selectorItem("spiffe://example.org/b", "unix:uid:0", "k8s:ns:default"),
+        },
+        Count:        2,
+        ScannedCount: 5,
+    }, nil)
+
+    filters := []Filter{{Name: "Selectors", Op: MatchSubset, Value: []string{"unix:uid:0", "unix:gid:0"}}}
+    count, err := CountItems(ctx, "EntriesTable", mockClient, filters)
+    assert.NoError(t, err)
+    assert.Equal(t, ItemCount{Count: 1, ScannedCount: 5}, count)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput)
+    assert.Empty(t, input.Select)
+    assert.Equal(t, []string{"Selectors"}, attributeNames(input))
+}
@@ -0,0 +1,320 @@
+package dynamodbstore
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
+
+// Combinadores para agrupar filtros em árvores
+type Combinator int
+
+const (
+    CombineAnd Combinator = iota + 1
+    CombineOr
+    CombineNot
+)
+
+// And agrupa filtros que devem ser todos satisfeitos
+func And(filters ...Filter) Filter {
+    return Filter{Combinator: CombineAnd, Filters: filters}
+}
+
+// Or agrupa filtros dos quais ao menos um deve ser satisfeito
+func Or(filters ...Filter) Filter {
+    return Filter{Combinator: CombineOr, Filters: filters}
+}
+
+// Not nega o filtro informado
+func Not(filter Filter) Filter {
+    return Filter{Combinator: CombineNot, Filters: []Filter{filter}}
+}
+
+// Aproximação de um filtro por condições que o DynamoDB sabe avaliar. Todo
+// item que satisfaz o filtro satisfaz relaxed, e todo item que satisfaz
+// strict satisfaz o filtro. Sem relaxed vale TRUE; sem strict vale FALSE.
+// exact indica que as duas condições são o próprio filtro e dispensa a
+// verificação local.
+type filterBounds struct {
+    relaxed, strict       expression.ConditionBuilder
+    hasRelaxed, hasStrict bool
+    exact                 bool
+}
+
+func exactBounds(condition expression.ConditionBuilder) filterBounds {
+    return filterBounds{relaxed: condition, strict: condition, hasRelaxed: true, hasStrict: true, exact: true}
+}
+
+// Compila a lista de filtros (combinados com AND) em uma única expressão.
+// Quando verify é verdadeiro a expressão apenas reduz a leitura e os itens
+// retornados ainda precisam passar por matchFilters.
+func buildFilterExpression(filters []Filter) (condition expression.ConditionBuilder, hasCondition, verify bool, err error) {
+    if len(filters) == 0 {
+        return condition, false, false, nil
+    }
+
+    bounds, err := filterBoundsOf(And(filters...))
+    if err != nil {
+        return condition, false, false, err
+    }
+    return bounds.relaxed, bounds.hasRelaxed, !bounds.exact, nil
+}
+
+// Percorre a árvore de filtros recursivamente
+func filterBoundsOf(filter Filter) (filterBounds, error) {
+    switch filter.Combinator {
+    case 0:
+        return leafBounds(filter)
+    case CombineNot:
+        child, err := filterBoundsOf(And(filter.Filters...))
+        if err != nil {
+            return filterBounds{}, err
+        }
+        return filterBounds{
+            relaxed:    expression.Not(child.strict),
+            strict:     expression.Not(child.relaxed),
+            hasRelaxed: child.hasStrict,
+            hasStrict:  child.hasRelaxed,
+            exact:      child.exact,
+        }, nil
+    case CombineAnd, CombineOr:
+        children := make([]filterBounds, 0, len(filter.Filters))
+        for _, child := range filter.Filters {
+            bounds, err := filterBoundsOf(child)
+            if err != nil {
+                return filterBounds{}, err
+            }
+            children = append(children, bounds)
+        }
+
+        switch len(children) {
+        case 0:
+            return filterBounds{}, errors.New("grupo de filtros vazio")
+        case 1:
+            return children[0], nil
+        }
+
+        var relaxed, strict []expression.ConditionBuilder
+        bounds := filterBounds{exact: true}
+        for _, child := range children {
+            if child.hasRelaxed {
+                relaxed = append(relaxed, child.relaxed)
+            }
+            if child.hasStrict {
+                strict = append(strict, child.strict)
+            }
+            bounds.exact = bounds.exact && child.exact
+        }
+
+        if filter.Combinator == CombineOr {
+            bounds.relaxed, bounds.hasRelaxed = combine(expression.Or, relaxed), len(relaxed) == len(children)
+            bounds.strict, bounds.hasStrict = combine(expression.Or, strict), len(strict) > 0
+        } else {
+            bounds.relaxed, bounds.hasRelaxed = combine(expression.And, relaxed), len(relaxed) > 0
+            bounds.strict, bounds.hasStrict = combine(expression.And, strict), len(strict) == len(children)
+        }
+        return bounds, nil
+    }
+
+    return filterBounds{}, fmt.Errorf("combinador desconhecido: %d", filter.Combinator)
+}
+
+func combine(join func(left, right expression.ConditionBuilder, other ...expression.ConditionBuilder) expression.ConditionBuilder, conditions []expression.ConditionBuilder) expression.ConditionBuilder {
+    switch len(conditions) {
+    case 0:
+        return expression.ConditionBuilder{}
+    case 1:
+        return conditions[0]
+    }
+    return join(conditions[0], conditions[1], conditions[2:]...)
+}
+
+func leafBounds(filter Filter) (filterBounds, error) {
+    switch filter.Op {
+    case MatchAny, MatchSuperset, MatchSubset:
+        return setBounds(filter)
+    }
+
+    condition, err := leafCondition(filter)
+    if err != nil {
+        return filterBounds{}, err
+    }
+    return exactBounds(condition), nil
+}
+
+// Operações de seletores no estilo do SPIRE. MatchAny e MatchSuperset viram
+// contains() diretamente; o DynamoDB não consegue verificar que todos os
+// elementos do atributo pertencem à lista, então MatchSubset só mantém itens
+// com algum elemento em comum e o restante fica para matchFilters.
+func setBounds(filter Filter) (filterBounds, error) {
+    values, err := setValues(filter)
+    if err != nil {
+        return filterBounds{}, err
+    }
+
+    field := expression.Name(filter.Name)
+    conditions := make([]expression.ConditionBuilder, len(values))
+    for i, value := range values {
+        conditions[i] = expression.Contains(field, value)
+    }
+
+    switch filter.Op {
+    case MatchAny:
+        return exactBounds(combine(expression.Or, conditions)), nil
+    case MatchSuperset:
+        return exactBounds(combine(expression.And, conditions)), nil
+    }
+    return filterBounds{relaxed: combine(expression.Or, conditions), hasRelaxed: true}, nil
+}
+
+// Elementos do Value de um filtro de conjunto ou In. Um valor escalar é
+// tratado como um conjunto de um elemento.
+func setValues(filter Filter) ([]interface{}, error) {
+    value := reflect.ValueOf(filter.Value)
+    if !value.IsValid() {
+        return nil, fmt.Errorf("filtro em %q precisa de um valor", filter.Name)
+    }
+    if (value.Kind() != reflect.Slice && value.Kind() != reflect.Array) || value.Type().Elem().Kind() == reflect.Uint8 {
+        return []interface{}{filter.Value}, nil
+    }
+
+    values := make([]interface{}, value.Len())
+    for i := range values {
+        values[i] = value.Index(i).Interface()
+    }
+    if len(values) == 0 {
+        return nil, fmt.Errorf("filtro em %q precisa de ao menos um valor", filter.Name)
+    }
+    return values, nil
+}
+
+// Define a expressão de acordo com o tipo de operação
+func leafCondition(filter Filter) (expression.ConditionBuilder, error) {
+    field := expression.Name(filter.Name)
+
+    switch filter.Op {
+    case EqualTo:
+        return field.Equal(expression.Value(filter.Value)), nil
+    case LessThan:
+        return field.LessThan(expression.Value(filter.Value)), nil
+    case GreaterThan:
+        return field.GreaterThan(expression.Value(filter.Value)), nil
+    case MatchExact:
+        return field.Equal(expression.Value(filter.Value)), nil
+    case LessOrEqual:
+        return field.LessThanEqual(expression.Value(filter.Value)), nil
+    case GreaterOrEqual:
+        return field.GreaterThanEqual(expression.Value(filter.Value)), nil
+    case Between:
+        bounds, err := rangeValue(filter.Name, filter.Value)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        return field.Between(expression.Value(bounds.Lower), expression.Value(bounds.Upper)), nil
+    case BeginsWith:
+        prefix, err := prefixValue(filter)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        return field.BeginsWith(prefix), nil
+    case NotEqual:
+        return field.NotEqual(expression.Value(filter.Value)), nil
+    case In:
+        values, err := setValues(filter)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        if len(values) > maxInValues {
+            return expression.ConditionBuilder{}, fmt.Errorf("filtro in em %q aceita no máximo %d valores, recebeu %d", filter.Name, maxInValues, len(values))
+        }
+        operands := make([]expression.OperandBuilder, len(values))
+        for i, value := range values {
+            operands[i] = expression.Value(value)
+        }
+        return field.In(operands[0], operands[1:]...), nil
+    case AttributeExists:
+        return field.AttributeExists(), nil
+    case AttributeNotExists:
+        return field.AttributeNotExists(), nil
+    case AttributeType:
+        attributeType, err := typeValue(filter)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        return field.AttributeType(attributeType), nil
+    case Size:
+        comparison, err := sizeValue(filter)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        return sizeCondition(filter.Name, field.Size(), comparison)
+    }
+
+    return expression.ConditionBuilder{}, fmt.Errorf("operação desconhecida para %q: %d", filter.Name, filter.Op)
+}
+
+// Quantidade máxima de operandos aceita pelo DynamoDB em uma lista IN
+const maxInValues = 100
+
+func sizeCondition(name string, size expression.SizeBuilder, comparison SizeComparison) (expression.ConditionBuilder, error) {
+    value := expression.Value(comparison.Value)
+
+    switch comparison.Op {
+    case EqualTo:
+        return size.Equal(value), nil
+    case NotEqual:
+        return size.NotEqual(value), nil
+    case LessThan:
+        return size.LessThan(value), nil
+    case LessOrEqual:
+        return size.LessThanEqual(value), nil
+    case GreaterThan:
+        return size.GreaterThan(value), nil
+    case GreaterOrEqual:
+        return size.GreaterThanEqual(value), nil
+    case Between:
+        bounds, err := rangeValue(name, comparison.Value)
+        if err != nil {
+            return expression.ConditionBuilder{}, err
+        }
+        return size.Between(expression.Value(bounds.Lower), expression.Value(bounds.Upper)), nil
+    }
+
+    return expression.ConditionBuilder{}, fmt.Errorf("comparação de tamanho não suportada para %q: %d", name, comparison.Op)
+}
+
+func rangeValue(name string, value interface{}) (Range, error) {
+    bounds, ok := value.(Range)
+    if !ok {
+        return Range{}, fmt.Errorf("filtro between em %q precisa de um valor Range, recebeu %T", name, value)
+    }
+    return bounds, nil
+}
+
+func prefixValue(filter Filter) (string, error) {
+    prefix, ok := filter.Value.(string)
+    if !ok {
+        return "", fmt.Errorf("filtro begins with em %q precisa de um valor string, recebeu %T", filter.Name, filter.Value)
+    }
+    return prefix, nil
+}
+
+func typeValue(filter Filter) (expression.DynamoDBAttributeType, error) {
+    switch attributeType := filter.Value.(type) {
+    case expression.DynamoDBAttributeType:
+        return attributeType, nil
+    case string:
+        return expression.DynamoDBAttributeType(attributeType), nil
+    }
+    return "", fmt.Errorf("filtro attribute type em %q precisa de um valor expression.DynamoDBAttributeType, recebeu %T", filter.Name, filter.Value)
+}
+
+func sizeValue(filter Filter) (SizeComparison, error) {
+    comparison, ok := filter.Value.(SizeComparison)
+    if !ok {
+        return SizeComparison{}, fmt.Errorf("filtro size em %q precisa de um valor SizeComparison, recebeu %T", filter.Name, filter.Value)
+    }
+    return comparison, nil
+}
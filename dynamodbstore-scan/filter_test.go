@@ -0,0 +1,121 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+// Retorna os valores dos nomes de atributos usados na expressão
+func attributeNames(input *dynamodb.ScanInput) []string {
+    var names []string
+    for _, name := range input.ExpressionAttributeNames {
+        names = append(names, name)
+    }
+    return names
+}
+
+func TestListItemsCombinesAllFilters(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{}, nil)
+
+    filters := []Filter{
+        {Name: "ParentID", Op: EqualTo, Value: "spiffe://example.org/parent"},
+        {Name: "SpiffeID", Op: EqualTo, Value: "spiffe://example.org/node"},
+    }
+    _, _, err := ListItems[Entry](ctx, "EntriesTable", mockClient, filters, nil, nil)
+    assert.NoError(t, err)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput)
+    assert.ElementsMatch(t, []string{"ParentID", "SpiffeID"}, attributeNames(input))
+    assert.Contains(t, *input.FilterExpression, "AND")
+}
+
+func TestListItemsFilterTree(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{}, nil)
+
+    filters := []Filter{
+        Or(
+            Filter{Name: "ParentID", Op: EqualTo, Value: "spiffe://example.org/a"},
+            Filter{Name: "ParentID", Op: EqualTo, Value: "spiffe://example.org/b"},
+        ),
+        Not(Filter{Name: "Downstream", Op: EqualTo, Value: true}),
+    }
+    _, _, err := ListItems[Entry](ctx, "EntriesTable", mockClient, filters, nil, nil)
+    assert.NoError(t, err)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput)
+    assert.ElementsMatch(t, []string{"ParentID", "Downstream"}, attributeNames(input))
+    assert.Contains(t, *input.FilterExpression, "OR")
+    assert.Contains(t, *input.FilterExpression, "NOT")
+    assert.Len(t, input.ExpressionAttributeValues, 3)
+}
+
+func TestListItemsRejectsInvalidFilters(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+
+    _, _, err := ListItems[Entry](ctx, "EntriesTable", mockClient, []Filter{Or()}, nil, nil)
+    assert.Error(t, err)
+
+    _, _, err = ListItems[Entry](ctx, "EntriesTable", mockClient, []Filter{{Name: "ParentID"}}, nil, nil)
+    assert.Error(t, err)
+
+    mockClient.AssertNotCalled(t, "Scan", mock.Anything, mock.Anything)
+}
+
+func TestListItemsExtendedOperators(t *testing.T) {
+    ctx := context.Background()
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+    cases := []struct {
+        filter   Filter
+        contains string
+    }{
+        {Filter{Name: "ExpiresAt", Op: LessOrEqual, Value: now}, "<="},
+        {Filter{Name: "ExpiresAt", Op: GreaterOrEqual, Value: now}, ">="},
+        {Filter{Name: "Banned", Op: AttributeNotExists}, "attribute_not_exists"},
+        {Filter{Name: "Banned", Op: AttributeExists}, "attribute_exists"},
+        {Filter{Name: "Token", Op: NotEqual, Value: "token123"}, "<>"},
+        {Filter{Name: "Token", Op: In, Value: []string{"a", "b", "c"}}, "IN"},
+        {Filter{Name: "Token", Op: BeginsWith, Value: "tok"}, "begins_with"},
+        {Filter{Name: "Selectors", Op: AttributeType, Value: expression.StringSet}, "attribute_type"},
+        {Filter{Name: "Selectors", Op: Size, Value: SizeComparison{Op: GreaterThan, Value: 2}}, "size"},
+        {Filter{Name: "Selectors", Op: Size, Value: SizeComparison{Op: Between, Value: Range{Lower: 1, Upper: 3}}}, "BETWEEN"},
+    }
+    for _, c := range cases {
+        mockClient := new(MockDynamoDBClient)
+        mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{}, nil)
+
+        _, _, err := ListItems[JoinToken](ctx, "JoinTokensTable", mockClient, []Filter{c.filter}, nil, nil)
+        assert.NoError(t, err)
+
+        input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput)
+        assert.Contains(t, *input.FilterExpression, c.contains)
+    }
+}
+
+func TestListItemsRejectsInvalidOperatorValues(t *testing.T) {
+    ctx := context.Background()
+    tooMany := make([]int, maxInValues+1)
+
+    for _, filter := range []Filter{
+        {Name: "Token", Op: In, Value: tooMany},
+        {Name: "Token", Op: In, Value: []string{}},
+        {Name: "Selectors", Op: Size, Value: 3},
+        {Name: "Selectors", Op: Size, Value: SizeComparison{Op: MatchAny, Value: 3}},
+        {Name: "Selectors", Op: AttributeType, Value: 3},
+    } {
+        mockClient := new(MockDynamoDBClient)
+        _, _, err := ListItems[JoinToken](ctx, "JoinTokensTable", mockClient, []Filter{filter}, nil, nil)
+        assert.Error(t, err, "%+v", filter)
+    }
+}
@@ -3,11 +3,12 @@ package dynamodbstore
 import (
     "context"
     "fmt"
+    "sort"
+    "time"
 
     "github.com/aws/aws-sdk-go-v2/service/dynamodb"
     "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
     "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
-    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 )
 
 // Definições de MatchBehavior para comportamentos de filtro
@@ -21,13 +22,41 @@ const (
     LessThan
     GreaterThan
     EqualTo
+    LessOrEqual
+    GreaterOrEqual
+    Between    // Value é um Range
+    BeginsWith // Value é o prefixo (string)
+    NotEqual
+    In                 // Value é uma lista de candidatos
+    AttributeExists    // Value é ignorado
+    AttributeNotExists // Value é ignorado
+    AttributeType      // Value é um expression.DynamoDBAttributeType
+    Size               // Value é um SizeComparison
 )
 
-// Estrutura de filtro para as operações de listagem
+// Limites inclusivos de um filtro Between
+type Range struct {
+    Lower interface{}
+    Upper interface{}
+}
+
+// Comparação do tamanho de um atributo em um filtro Size. Op é EqualTo,
+// NotEqual, uma das comparações de ordem, ou Between com Value do tipo Range.
+type SizeComparison struct {
+    Op    MatchBehavior
+    Value interface{}
+}
+
+// Estrutura de filtro para as operações de listagem. Um filtro com
+// Combinator definido é um grupo: Name, Op e Value são ignorados e os
+// filtros em Filters são combinados com And, Or ou Not.
 type Filter struct {
     Name  string
     Op    MatchBehavior
     Value interface{}
+
+    Combinator Combinator
+    Filters    []Filter
 }
 
 // Estrutura de paginação para controle dos resultados
@@ -35,6 +64,9 @@ type Pagination struct {
     Token     string // Token para a próxima página
     Limit     int    // Limite de itens por página
     NextToken string // Token atualizado após a consulta
+
+    Signer *TokenSigner // Assina os tokens e rejeita os adulterados (opcional)
+    Fill   bool         // Limit passa a contar itens retornados, não avaliados
 }
 
 // Interface para simular o cliente DynamoDB
@@ -42,56 +74,81 @@ type DynamoDBAPI interface {
     Scan(ctx context.Context, input *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
 }
 
-// Função genérica de listagem para DynamoDB
-func ListItems[T any](ctx context.Context, tableName string, dynamoClient DynamoDBAPI, filters []Filter, pagination *Pagination, projection []string) ([]T, *Pagination, error) {
-    var filterExpression expression.ConditionBuilder
-    hasFilters := false
-
-    for _, filter := range filters {
-        field := expression.Name(filter.Name)
-
-        // Define a expressão de acordo com o tipo de operação
-        switch filter.Op {
-        case EqualTo:
-            filterExpression = field.Equal(expression.Value(filter.Value))
-        case LessThan:
-            filterExpression = field.LessThan(expression.Value(filter.Value))
-        case GreaterThan:
-            filterExpression = field.GreaterThan(expression.Value(filter.Value))
-        case MatchAny:
-            filterExpression = expression.Contains(field, filter.Value)
-        case MatchExact:
-            filterExpression = field.Equal(expression.Value(filter.Value))
-        case MatchSuperset:
-            filterExpression = expression.Contains(field, expression.Value(filter.Value))
-        case MatchSubset:
-            filterExpression = expression.Contains(field, expression.Value(filter.Value))
-        }
-        hasFilters = true
+// Scan montado a partir dos filtros e da projeção, com o necessário para
+// tratar as páginas retornadas
+type scanRequest struct {
+    input            *dynamodb.ScanInput
+    fingerprint      string
+    filters          []Filter
+    projection       []string // Projeção pedida pelo chamador
+    expiry           string   // Atributo da expiração com ExcludeExpired
+    verify           bool
+    verificationOnly []string // Atributos projetados apenas para a verificação local
+    keysKept         bool
+}
+
+// Com expiry, só lê itens cujo atributo expiry não passou (ExcludeExpired)
+func newScanRequest(tableName string, filters []Filter, projection []string, expiry string) (*scanRequest, error) {
+    requested := projection
+    filterExpression, hasFilters, verify, err := buildFilterExpression(filters)
+    if err != nil {
+        return nil, fmt.Errorf("erro ao construir filtro: %w", err)
+    }
+
+    // Atributos necessários apenas para a verificação local dos filtros
+    var verificationOnly []string
+    if verify {
+        projection, verificationOnly = verificationProjection(projection, filters)
     }
 
-    // Configuração da projeção de atributos
+    build := func(filterExpression expression.ConditionBuilder, hasFilters bool) (expression.Expression, error) {
+        // Sem filtro nem projeção o Builder fica vazio e Build falharia; a
+        // expressão vazia deixa os campos de expressão do Scan como nil
+        if !hasFilters && len(projection) == 0 {
+            return expression.Expression{}, nil
+        }
 
-    // Construindo a projeção de forma incremental
-    builder := expression.NewBuilder()
-    if len(projection) > 0 {
-        // Inicia a projeção com o primeiro campo
-        projBuilder := expression.NamesList(expression.Name(projection[0]))
-        for _, attr := range projection[1:] {
-            // Adiciona os outros campos ao projBuilder
-            projBuilder = projBuilder.AddNames(expression.Name(attr))
+        // Construindo a projeção de forma incremental
+        builder := expression.NewBuilder()
+        if len(projection) > 0 {
+            // Inicia a projeção com o primeiro campo
+            projBuilder := expression.NamesList(expression.Name(projection[0]))
+            for _, attr := range projection[1:] {
+                // Adiciona os outros campos ao projBuilder
+                projBuilder = projBuilder.AddNames(expression.Name(attr))
+            }
+            builder = builder.WithProjection(projBuilder)
         }
-        builder = builder.WithProjection(projBuilder)
-    }
 
-    // Adicione o filtro apenas se houver filtros definidos
-    if hasFilters {
-        builder = builder.WithFilter(filterExpression)
+        // Adicione o filtro apenas se houver filtros definidos
+        if hasFilters {
+            builder = builder.WithFilter(filterExpression)
+        }
+
+        expr, err := builder.Build()
+        if err != nil {
+            return expression.Expression{}, fmt.Errorf("erro ao construir expressão: %w", err)
+        }
+        return expr, nil
     }
 
-    expr, err := builder.Build()
+    expr, err := build(filterExpression, hasFilters)
     if err != nil {
-        return nil, nil, fmt.Errorf("erro ao construir expressão: %w", err)
+        return nil, err
+    }
+    fingerprint := queryFingerprint(tableName, expr, expiry)
+
+    // O filtro de expiração muda com o relógio, então fica fora do
+    // fingerprint e os tokens continuam válidos de uma página para a outra
+    if expiry != "" {
+        unexpired := unexpiredCondition(expiry, time.Now())
+        if hasFilters {
+            unexpired = expression.And(filterExpression, unexpired)
+        }
+        filterExpression, hasFilters = unexpired, true
+        if expr, err = build(filterExpression, hasFilters); err != nil {
+            return nil, err
+        }
     }
 
     input := &dynamodb.ScanInput{
@@ -105,10 +162,98 @@ func ListItems[T any](ctx context.Context, tableName string, dynamoClient Dynamo
         input.FilterExpression = expr.Filter()
     }
 
+    return &scanRequest{
+        input:            input,
+        fingerprint:      fingerprint,
+        filters:          filters,
+        projection:       requested,
+        expiry:           expiry,
+        verify:           verify,
+        verificationOnly: verificationOnly,
+    }, nil
+}
+
+// Acrescenta à projeção os atributos da chave, conhecidos pelo
+// LastEvaluatedKey, para que uma página cortada possa ser retomada do último
+// item retornado. Os atributos acrescentados são removidos dos itens, e o
+// fingerprint continua o da projeção pedida. O paginador copia input a cada
+// página, então a mudança vale a partir da próxima.
+func (r *scanRequest) keepKeys(lastKey map[string]types.AttributeValue) error {
+    if r.keysKept || len(r.projection) == 0 {
+        return nil
+    }
+    r.keysKept = true
+
+    names := make([]string, 0, len(lastKey))
+    for name := range lastKey {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    projection, added := extendProjection(r.projection, names)
+    if len(added) == 0 {
+        return nil
+    }
+
+    extended, err := newScanRequest(*r.input.TableName, r.filters, projection, r.expiry)
+    if err != nil {
+        return err
+    }
+    r.input.ProjectionExpression = extended.input.ProjectionExpression
+    r.input.FilterExpression = extended.input.FilterExpression
+    r.input.ExpressionAttributeNames = extended.input.ExpressionAttributeNames
+    r.input.ExpressionAttributeValues = extended.input.ExpressionAttributeValues
+    r.verificationOnly = append(extended.verificationOnly, added...)
+    return nil
+}
+
+// Mantém apenas os itens da página que passam pela verificação local
+func (r *scanRequest) verifyPage(items []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+    if !r.verify {
+        return items, nil
+    }
+    items, err := verifyItems(r.filters, items)
+    if err != nil {
+        return nil, fmt.Errorf("falha ao verificar registros: %w", err)
+    }
+    return items, nil
+}
+
+// Função genérica de listagem para DynamoDB. As opções limitam a leitura
+// (WithMaxItems, WithMaxPages, WithCapacityBudget); quando um limite
+// interrompe o Scan, NextToken retoma do ponto em que parou, mesmo que
+// pagination seja nil.
+func ListItems[T any](ctx context.Context, tableName string, dynamoClient DynamoDBAPI, filters []Filter, pagination *Pagination, projection []string, opts ...Option) ([]T, *Pagination, error) {
+    settings, err := withExpiry[T](newOptions(opts))
+    if err != nil {
+        return nil, nil, err
+    }
+
+    times, err := timeCodecFor[T]()
+    if err != nil {
+        return nil, nil, err
+    }
+
+    request, err := newScanRequest(tableName, times.encodeFilters(filters), projection, settings.expiry)
+    if err != nil {
+        return nil, nil, err
+    }
+    input := request.input
+
+    // Com Fill, lê quantas páginas forem necessárias para juntar Limit itens.
+    // Uma página cortada é retomada a partir da chave do último item
+    // retornado; os atributos da chave que faltarem na projeção são lidos a
+    // partir da segunda página.
+    fill := pagination != nil && pagination.Fill && pagination.Limit > 0
+
+    maxItems := settings.maxItems
+    if fill && (maxItems <= 0 || pagination.Limit < maxItems) {
+        maxItems = pagination.Limit
+    }
+
     // Configuração de paginação
     if pagination != nil && pagination.Token != "" {
-        input.ExclusiveStartKey = map[string]types.AttributeValue{
-            "Key": &types.AttributeValueMemberS{Value: pagination.Token},
+        if input.ExclusiveStartKey, err = decodeToken(tableName, request.fingerprint, pagination.Token, pagination.Signer); err != nil {
+            return nil, nil, err
         }
     }
 
@@ -117,23 +262,84 @@ func ListItems[T any](ctx context.Context, tableName string, dynamoClient Dynamo
         input.Limit = &limit
     }
 
+    // A primeira página nunca traz mais itens que o limite, então só é
+    // preciso cortar páginas seguintes, quando a chave já é conhecida
+    if maxItems > 0 && (input.Limit == nil || int(*input.Limit) > maxItems) {
+        limit := int32(maxItems)
+        input.Limit = &limit
+    }
+
+    if settings.capacityBudget > 0 {
+        input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+    }
+
+    // Limpa o token de uma chamada anterior; só é preenchido se houver mais páginas
+    if pagination != nil {
+        pagination.NextToken = ""
+    }
+
     var results []T
+    var keyNames map[string]types.AttributeValue
+    var pages int
+    var consumed float64
     scanPaginator := dynamodb.NewScanPaginator(dynamoClient, input)
     for scanPaginator.HasMorePages() {
         page, err := scanPaginator.NextPage(ctx)
         if err != nil {
             return nil, nil, fmt.Errorf("falha ao buscar registros: %w", err)
         }
+        pages++
+        if page.ConsumedCapacity != nil && page.ConsumedCapacity.CapacityUnits != nil {
+            consumed += *page.ConsumedCapacity.CapacityUnits
+        }
+
+        items, err := request.verifyPage(page.Items)
+        if err != nil {
+            return nil, nil, err
+        }
 
-        var pageResults []T
-        if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageResults); err != nil {
+        lastKey := page.LastEvaluatedKey
+        if lastKey != nil {
+            keyNames = lastKey
+            if maxItems > 0 {
+                if err := request.keepKeys(lastKey); err != nil {
+                    return nil, nil, err
+                }
+            }
+        }
+        full := false
+        if maxItems > 0 {
+            if need := maxItems - len(results); len(items) >= need {
+                full = true
+                if len(items) > need {
+                    items = items[:need]
+                    if lastKey, err = itemKey(items[need-1], keyNames); err != nil {
+                        return nil, nil, fmt.Errorf("falha ao gerar token de paginação: %w", err)
+                    }
+                }
+            }
+        }
+        stripAttributes(items, request.verificationOnly)
+
+        pageResults, err := unmarshalRecords[T](times, items)
+        if err != nil {
             return nil, nil, fmt.Errorf("falha ao deserializar registros: %w", err)
         }
 
         results = append(results, pageResults...)
 
-        if pagination != nil && page.LastEvaluatedKey != nil {
-            pagination.NextToken = page.LastEvaluatedKey["Key"].(*types.AttributeValueMemberS).Value
+        stop := full || (pagination != nil && !fill) ||
+            (settings.maxPages > 0 && pages >= settings.maxPages) ||
+            (settings.capacityBudget > 0 && consumed >= settings.capacityBudget)
+        if stop {
+            if lastKey != nil {
+                if pagination == nil {
+                    pagination = &Pagination{}
+                }
+                if pagination.NextToken, err = encodeToken(tableName, request.fingerprint, lastKey, pagination.Signer); err != nil {
+                    return nil, nil, fmt.Errorf("falha ao gerar token de paginação: %w", err)
+                }
+            }
             break
         }
     }
@@ -0,0 +1,58 @@
+package dynamodbstore
+
+import (
+    "context"
+    "fmt"
+    "iter"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Variante de ListItems que percorre a tabela sob demanda, uma página do
+// Scan por vez, sem acumular os resultados. Um break no range encerra a
+// leitura; depois de um erro a sequência termina.
+func ListItemsIter[T any](ctx context.Context, tableName string, dynamoClient DynamoDBAPI, filters []Filter, projection []string) iter.Seq2[T, error] {
+    return func(yield func(T, error) bool) {
+        var zero T
+
+        times, err := timeCodecFor[T]()
+        if err != nil {
+            yield(zero, err)
+            return
+        }
+
+        request, err := newScanRequest(tableName, times.encodeFilters(filters), projection, "")
+        if err != nil {
+            yield(zero, err)
+            return
+        }
+
+        scanPaginator := dynamodb.NewScanPaginator(dynamoClient, request.input)
+        for scanPaginator.HasMorePages() {
+            page, err := scanPaginator.NextPage(ctx)
+            if err != nil {
+                yield(zero, fmt.Errorf("falha ao buscar registros: %w", err))
+                return
+            }
+
+            items, err := request.verifyPage(page.Items)
+            if err != nil {
+                yield(zero, err)
+                return
+            }
+            stripAttributes(items, request.verificationOnly)
+
+            pageResults, err := unmarshalRecords[T](times, items)
+            if err != nil {
+                yield(zero, fmt.Errorf("falha ao deserializar registros: %w", err))
+                return
+            }
+
+            for _, result := range pageResults {
+                if !yield(result, nil) {
+                    return
+                }
+            }
+        }
+    }
+}
@@ -0,0 +1,42 @@
+package dynamodbstore
+
+// Opção que ajusta a leitura feita por ListItems
+type Option func(*options)
+
+type options struct {
+    maxItems       int
+    maxPages       int
+    capacityBudget float64
+    excludeExpired bool
+    expiry         string // Atributo da expiração, resolvido pelo tipo do item
+}
+
+func newOptions(opts []Option) options {
+    var o options
+    for _, opt := range opts {
+        opt(&o)
+    }
+    return o
+}
+
+// Para a leitura depois de retornar n itens
+func WithMaxItems(n int) Option {
+    return func(o *options) {
+        o.maxItems = n
+    }
+}
+
+// Para a leitura depois de n páginas do Scan
+func WithMaxPages(n int) Option {
+    return func(o *options) {
+        o.maxPages = n
+    }
+}
+
+// Pede ReturnConsumedCapacity e para a leitura quando a capacidade consumida
+// atinge units. A página que ultrapassa o orçamento ainda é retornada.
+func WithCapacityBudget(units float64) Option {
+    return func(o *options) {
+        o.capacityBudget = units
+    }
+}
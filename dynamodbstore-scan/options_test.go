@@ -0,0 +1,119 @@
+package dynamodbstore
+
+import (
+    "context"
+    "testing"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func bundlePage(lastKey string, ids ...string) *dynamodb.ScanOutput {
+    units := 3.0
+    output := &dynamodb.ScanOutput{ConsumedCapacity: &types.ConsumedCapacity{CapacityUnits: &units}}
+    for _, id := range ids {
+        output.Items = append(output.Items, bundleItem(id))
+    }
+    if lastKey != "" {
+        output.LastEvaluatedKey = bundleItem(lastKey)
+    }
+    return output
+}
+
+func TestListItemsMaxItems(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(bundlePage("b", "a", "b"), nil).Once()
+    mockClient.On("Scan", ctx, mock.Anything).Return(bundlePage("e", "c", "d", "e"), nil).Once()
+
+    results, pagination, err := ListItems[Bundle](ctx, "BundlesTable", mockClient, nil, nil, nil, WithMaxItems(3))
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Equal(t, []Bundle{{ID: "a"}, {ID: "b"}, {ID: "c"}}, results)
+    assert.Equal(t, int32(3), *mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput).Limit)
+
+    // Mesmo sem pagination a chamada retorna um token para continuar
+    if assert.NotNil(t, pagination) {
+        mockClient.On("Scan", ctx, mock.Anything).Return(bundlePage("", "d", "e"), nil).Once()
+        results, pagination, err = ListItems[Bundle](ctx, "BundlesTable", mockClient, nil, &Pagination{Token: pagination.NextToken}, nil, WithMaxItems(3))
+        assert.NoError(t, err)
+        assert.Equal(t, []Bundle{{ID: "d"}, {ID: "e"}}, results)
+        assert.Empty(t, pagination.NextToken)
+        assert.Equal(t, bundleItem("c"), mockClient.Calls[2].Arguments.Get(1).(*dynamodb.ScanInput).ExclusiveStartKey)
+    }
+}
+
+func TestListItemsMaxItemsWithFilter(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(bundlePage("b", "a", "b"), nil).Once()
+    mockClient.On("Scan", ctx, mock.Anything).Return(bundlePage("", "c", "d"), nil).Once()
+
+    filters := []Filter{{Name: "ID", Op: BeginsWith, Value: "bundle"}}
+    results, pagination, err := ListItems[Bundle](ctx, "BundlesTable", mockClient, filters, nil, []string{"ID"}, WithMaxItems(3))
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Equal(t, []Bundle{{ID: "a"}, {ID: "b"}, {ID: "c"}}, results)
+    if assert.NotNil(t, pagination) {
+        assert.NotEmpty(t, pagination.NextToken)
+    }
+
+    // O limite vale junto com o filtro e a projeção em todas as páginas
+    for _, call := range mockClient.Calls {
+        input := call.Arguments.Get(1).(*dynamodb.ScanInput)
+        assert.NotNil(t, input.FilterExpression)
+        assert.Equal(t, int32(3), *input.Limit)
+        assert.Contains(t, attributeNames(input), "ID")
+    }
+}
+
+func TestListItemsMaxPages(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(bundlePage("a", "a"), nil).Once()
+    mockClient.On("Scan", ctx, mock.Anything).Return(bundlePage("b", "b"), nil).Once()
+
+    results, pagination, err := ListItems[Bundle](ctx, "BundlesTable", mockClient, nil, nil, nil, WithMaxPages(2))
+    assert.NoError(t, err)
+    assert.Len(t, results, 2)
+    if assert.NotNil(t, pagination) {
+        assert.NotEmpty(t, pagination.NextToken)
+    }
+    mockClient.AssertNumberOfCalls(t, "Scan", 2)
+}
+
+func TestListItemsCapacityBudget(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(bundlePage("a", "a"), nil).Once()
+    mockClient.On("Scan", ctx, mock.Anything).Return(bundlePage("b", "b"), nil).Once()
+
+    results, pagination, err := ListItems[Bundle](ctx, "BundlesTable", mockClient, nil, nil, nil, WithCapacityBudget(5))
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Len(t, results, 2)
+    if assert.NotNil(t, pagination) {
+        assert.NotEmpty(t, pagination.NextToken)
+    }
+    mockClient.AssertNumberOfCalls(t, "Scan", 2)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput)
+    assert.Equal(t, types.ReturnConsumedCapacityTotal, input.ReturnConsumedCapacity)
+}
+
+func TestListItemsWithoutCapsReadsEverything(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(bundlePage("a", "a"), nil).Once()
+    mockClient.On("Scan", ctx, mock.Anything).Return(bundlePage("", "b"), nil).Once()
+
+    results, pagination, err := ListItems[Bundle](ctx, "BundlesTable", mockClient, nil, nil, nil, WithMaxPages(5), WithCapacityBudget(100))
+    assert.NoError(t, err)
+    assert.Len(t, results, 2)
+    assert.Nil(t, pagination)
+}
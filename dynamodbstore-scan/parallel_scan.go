@@ -0,0 +1,205 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Valor máximo de TotalSegments aceito pelo DynamoDB
+const maxSegments = 1000000
+
+// Posição de um segmento do scan paralelo; done indica que ele foi lido até o fim
+type segmentPosition struct {
+    key  map[string]types.AttributeValue
+    done bool
+}
+
+// Scan paralelo: divide a tabela em segments segmentos (Segment/TotalSegments)
+// lidos por até workers goroutines ao mesmo tempo e junta os itens
+// decodificados em um único resultado, sem ordem definida entre os
+// segmentos. Com pagination, cada segmento ainda não terminado lê uma página
+// (Limit vale por segmento) e NextToken guarda a posição de todos eles; a
+// chamada seguinte precisa usar o mesmo número de segmentos, mas pode mudar
+// o de workers. Fill não é suportado.
+func ListItemsParallel[T any](ctx context.Context, tableName string, dynamoClient DynamoDBAPI, filters []Filter, pagination *Pagination, projection []string, segments, workers int) ([]T, *Pagination, error) {
+    if segments < 1 || segments > maxSegments {
+        return nil, nil, fmt.Errorf("número de segmentos deve estar entre 1 e %d, recebeu %d", maxSegments, segments)
+    }
+    if workers < 1 {
+        return nil, nil, fmt.Errorf("número de workers deve ser positivo, recebeu %d", workers)
+    }
+    if pagination != nil && pagination.Fill {
+        return nil, nil, errors.New("Fill não é suportado no scan paralelo")
+    }
+
+    times, err := timeCodecFor[T]()
+    if err != nil {
+        return nil, nil, err
+    }
+
+    request, err := newScanRequest(tableName, times.encodeFilters(filters), projection, "")
+    if err != nil {
+        return nil, nil, err
+    }
+
+    positions := make([]segmentPosition, segments)
+    if pagination != nil && pagination.Token != "" {
+        if positions, err = decodeSegmentsToken(tableName, request.fingerprint, pagination.Token, segments, pagination.Signer); err != nil {
+            return nil, nil, err
+        }
+    }
+
+    if pagination != nil {
+        pagination.NextToken = ""
+    }
+
+    // Fila com os segmentos ainda não terminados
+    queue := make(chan int, segments)
+    for segment := range positions {
+        if !positions[segment].done {
+            queue <- segment
+        }
+    }
+    close(queue)
+    workers = min(workers, len(queue))
+
+    // Um erro em qualquer segmento cancela os demais
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    // Cada worker envia no máximo um erro
+    pages := make(chan []T)
+    errs := make(chan error, workers)
+    var wg sync.WaitGroup
+    for range workers {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for segment := range queue {
+                if err := ctx.Err(); err != nil {
+                    errs <- err
+                    return
+                }
+                if err := scanSegment(ctx, dynamoClient, request, times, pagination, segment, segments, &positions[segment], pages); err != nil {
+                    errs <- err
+                    cancel()
+                    return
+                }
+            }
+        }()
+    }
+    go func() {
+        wg.Wait()
+        close(pages)
+    }()
+
+    var results []T
+    for page := range pages {
+        results = append(results, page...)
+    }
+
+    select {
+    case err := <-errs:
+        return nil, nil, err
+    default:
+    }
+
+    if pagination != nil {
+        if pagination.NextToken, err = encodeSegmentsToken(tableName, request.fingerprint, positions, pagination.Signer); err != nil {
+            return nil, nil, fmt.Errorf("falha ao gerar token de paginação: %w", err)
+        }
+    }
+    return results, pagination, nil
+}
+
+// Lê um segmento a partir de position e envia cada página decodificada para
+// pages. Com pagination lê uma única página.
+func scanSegment[T any](ctx context.Context, dynamoClient DynamoDBAPI, request *scanRequest, times timeCodec, pagination *Pagination, segment, total int, position *segmentPosition, pages chan<- []T) error {
+    input := *request.input
+    segmentID, totalSegments := int32(segment), int32(total)
+    input.Segment, input.TotalSegments = &segmentID, &totalSegments
+    input.ExclusiveStartKey = position.key
+
+    if pagination != nil && pagination.Limit > 0 {
+        limit := int32(pagination.Limit)
+        input.Limit = &limit
+    }
+
+    scanPaginator := dynamodb.NewScanPaginator(dynamoClient, &input)
+    for scanPaginator.HasMorePages() {
+        page, err := scanPaginator.NextPage(ctx)
+        if err != nil {
+            return fmt.Errorf("falha ao buscar registros do segmento %d: %w", segment, err)
+        }
+
+        items, err := request.verifyPage(page.Items)
+        if err != nil {
+            return err
+        }
+        stripAttributes(items, request.verificationOnly)
+
+        pageResults, err := unmarshalRecords[T](times, items)
+        if err != nil {
+            return fmt.Errorf("falha ao deserializar registros: %w", err)
+        }
+        pages <- pageResults
+
+        position.key, position.done = page.LastEvaluatedKey, page.LastEvaluatedKey == nil
+        if pagination != nil {
+            break
+        }
+    }
+    return nil
+}
+
+// Token composto com a posição de cada segmento; vazio quando todos terminaram
+func encodeSegmentsToken(table, fingerprint string, positions []segmentPosition, signer *TokenSigner) (string, error) {
+    segments := make([]segmentToken, len(positions))
+    finished := true
+    for i, position := range positions {
+        if position.done {
+            segments[i].Done = true
+            continue
+        }
+        finished = false
+
+        var err error
+        if segments[i].Key, err = encodeKey(position.key); err != nil {
+            return "", err
+        }
+    }
+    if finished {
+        return "", nil
+    }
+    return sealToken(pageToken{Table: table, Query: fingerprint, Segments: segments}, signer)
+}
+
+func decodeSegmentsToken(table, fingerprint, encoded string, total int, signer *TokenSigner) ([]segmentPosition, error) {
+    token, err := openToken(table, fingerprint, encoded, signer)
+    if err != nil {
+        return nil, err
+    }
+    if len(token.Segments) != total {
+        return nil, &TokenError{Reason: TokenWrongQuery, Err: fmt.Errorf("token tem %d segmentos, o scan usa %d", len(token.Segments), total)}
+    }
+
+    positions := make([]segmentPosition, total)
+    for i, segment := range token.Segments {
+        if segment.Done {
+            positions[i].done = true
+            continue
+        }
+        if len(segment.Key) == 0 {
+            return nil, &TokenError{Reason: TokenMalformed, Err: fmt.Errorf("segmento %d sem chave", i)}
+        }
+        if positions[i].key, err = decodeKey(segment.Key); err != nil {
+            return nil, err
+        }
+    }
+    return positions, nil
+}
@@ -0,0 +1,153 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func segment(n int32) interface{} {
+    return mock.MatchedBy(func(input *dynamodb.ScanInput) bool {
+        return input.Segment != nil && *input.Segment == n && *input.TotalSegments == 3
+    })
+}
+
+func TestListItemsParallelMergesSegments(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", mock.Anything, segment(0)).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{bundleItem("a")}, LastEvaluatedKey: bundleItem("a")}, nil).Once()
+    mockClient.On("Scan", mock.Anything, segment(0)).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{bundleItem("b")}}, nil).Once()
+    mockClient.On("Scan", mock.Anything, segment(1)).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{bundleItem("c")}}, nil).Once()
+    mockClient.On("Scan", mock.Anything, segment(2)).Return(&dynamodb.ScanOutput{}, nil).Once()
+
+    results, _, err := ListItemsParallel[Bundle](ctx, "BundlesTable", mockClient, nil, nil, nil, 3, 2)
+    assert.NoError(t, err)
+    assert.ElementsMatch(t, []Bundle{{ID: "a"}, {ID: "b"}, {ID: "c"}}, results)
+    mockClient.AssertExpectations(t)
+}
+
+func TestListItemsParallelFiltersEachSegment(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", mock.Anything, mock.Anything).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{bundleItem("a")}}, nil)
+
+    filters := []Filter{{Name: "ID", Op: EqualTo, Value: "a"}}
+    results, _, err := ListItemsParallel[Bundle](ctx, "BundlesTable", mockClient, filters, nil, []string{"ID"}, 3, 2)
+    assert.NoError(t, err)
+    assert.Len(t, results, 3)
+
+    // Todos os segmentos levam o mesmo filtro e a mesma projeção
+    for _, call := range mockClient.Calls {
+        input := call.Arguments.Get(1).(*dynamodb.ScanInput)
+        assert.NotNil(t, input.FilterExpression)
+        assert.NotNil(t, input.ProjectionExpression)
+        assert.ElementsMatch(t, []string{"ID"}, attributeNames(input))
+    }
+}
+
+func TestListItemsParallelResumesEachSegment(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", mock.Anything, segment(0)).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{bundleItem("a")}, LastEvaluatedKey: bundleItem("a")}, nil).Once()
+    mockClient.On("Scan", mock.Anything, segment(1)).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{bundleItem("c")}}, nil).Once()
+    mockClient.On("Scan", mock.Anything, segment(2)).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{bundleItem("d")}, LastEvaluatedKey: bundleItem("d")}, nil).Once()
+
+    pagination := &Pagination{Limit: 1}
+    results, pagination, err := ListItemsParallel[Bundle](ctx, "BundlesTable", mockClient, nil, pagination, nil, 3, 2)
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Len(t, results, 3)
+    assert.NotEmpty(t, pagination.NextToken)
+
+    // O segmento 1 terminou e não é lido de novo
+    mockClient.On("Scan", mock.Anything, segment(0)).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{bundleItem("b")}}, nil).Once()
+    mockClient.On("Scan", mock.Anything, segment(2)).Return(&dynamodb.ScanOutput{}, nil).Once()
+
+    pagination.Token = pagination.NextToken
+    results, pagination, err = ListItemsParallel[Bundle](ctx, "BundlesTable", mockClient, nil, pagination, nil, 3, 2)
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Equal(t, []Bundle{{ID: "b"}}, results)
+    assert.Empty(t, pagination.NextToken)
+    if !mockClient.AssertExpectations(t) {
+        return
+    }
+
+    for _, call := range mockClient.Calls[3:] {
+        input := call.Arguments.Get(1).(*dynamodb.ScanInput)
+        assert.Equal(t, bundleItem(map[int32]string{0: "a", 2: "d"}[*input.Segment]), input.ExclusiveStartKey)
+    }
+}
+
+func TestListItemsParallelRejectsTokenForOtherSegmentCount(t *testing.T) {
+    ctx := context.Background()
+    request, err := newScanRequest("BundlesTable", nil, nil, "")
+    assert.NoError(t, err)
+
+    token, err := encodeSegmentsToken("BundlesTable", request.fingerprint, []segmentPosition{{key: bundleItem("a")}, {done: true}}, nil)
+    assert.NoError(t, err)
+
+    positions, err := decodeSegmentsToken("BundlesTable", request.fingerprint, token, 2, nil)
+    assert.NoError(t, err)
+    assert.Equal(t, []segmentPosition{{key: bundleItem("a")}, {done: true}}, positions)
+
+    mockClient := new(MockDynamoDBClient)
+    _, _, err = ListItemsParallel[Bundle](ctx, "BundlesTable", mockClient, nil, &Pagination{Token: token}, nil, 3, 2)
+    assert.ErrorIs(t, err, ErrInvalidToken)
+    mockClient.AssertNotCalled(t, "Scan", mock.Anything, mock.Anything)
+}
+
+func TestListItemsParallelReturnsSegmentErrors(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", mock.Anything, segment(0)).Return(&dynamodb.ScanOutput{}, nil)
+    mockClient.On("Scan", mock.Anything, segment(1)).Return((*dynamodb.ScanOutput)(nil), errors.New("throttled"))
+    mockClient.On("Scan", mock.Anything, segment(2)).Return(&dynamodb.ScanOutput{}, nil)
+
+    _, _, err := ListItemsParallel[Bundle](ctx, "BundlesTable", mockClient, nil, nil, nil, 3, 2)
+    assert.ErrorContains(t, err, "throttled")
+
+    _, _, err = ListItemsParallel[Bundle](ctx, "BundlesTable", mockClient, nil, nil, nil, 0, 1)
+    assert.Error(t, err)
+    _, _, err = ListItemsParallel[Bundle](ctx, "BundlesTable", mockClient, nil, nil, nil, 3, 0)
+    assert.Error(t, err)
+}
+
+func TestListItemsParallelBoundsWorkers(t *testing.T) {
+    ctx := context.Background()
+    var mu sync.Mutex
+    var running, peak int
+    var scanned []int32
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+        mu.Lock()
+        running++
+        peak = max(peak, running)
+        scanned = append(scanned, *args.Get(1).(*dynamodb.ScanInput).Segment)
+        mu.Unlock()
+
+        time.Sleep(5 * time.Millisecond)
+
+        mu.Lock()
+        running--
+        mu.Unlock()
+    }).Return(&dynamodb.ScanOutput{}, nil)
+
+    _, _, err := ListItemsParallel[Bundle](ctx, "BundlesTable", mockClient, nil, nil, nil, 8, 2)
+    assert.NoError(t, err)
+    assert.LessOrEqual(t, peak, 2)
+    assert.ElementsMatch(t, []int32{0, 1, 2, 3, 4, 5, 6, 7}, scanned)
+    for _, call := range mockClient.Calls {
+        assert.Equal(t, int32(8), *call.Arguments.Get(1).(*dynamodb.ScanInput).TotalSegments)
+    }
+}
@@ -0,0 +1,82 @@
+package dynamodbstore
+
+import (
+    "reflect"
+    "strings"
+)
+
+// Tag com as opções que o pacote lê de um campo, como `dynamodbstore:"ttl"`
+const tagName = "dynamodbstore"
+
+// Campo de um tipo de item com o atributo em que é gravado e suas opções
+type taggedField struct {
+    name      string
+    index     []int
+    attribute string
+    options   []string
+}
+
+// Procura uma opção que pode ter valor, como "ttl=Expiry"
+func (f taggedField) option(name string) (value string, ok bool) {
+    for _, o := range f.options {
+        key, value, _ := strings.Cut(o, "=")
+        if key == name {
+            return value, true
+        }
+    }
+    return "", false
+}
+
+// Campos de itemType com a tag dynamodbstore, com os nomes de atributo que o
+// attributevalue usa
+func taggedFields(itemType reflect.Type) []taggedField {
+    if itemType.Kind() != reflect.Struct {
+        return nil
+    }
+
+    var fields []taggedField
+    for _, field := range reflect.VisibleFields(itemType) {
+        tag, ok := field.Tag.Lookup(tagName)
+        attribute, stored := attributeName(field)
+        if !ok || !stored {
+            continue
+        }
+        fields = append(fields, taggedField{
+            name:      field.Name,
+            index:     field.Index,
+            attribute: attribute,
+            options:   strings.Split(tag, ","),
+        })
+    }
+    return fields
+}
+
+// Atributos que o attributevalue grava para itemType
+func itemAttributes(itemType reflect.Type) map[string]bool {
+    attributes := make(map[string]bool)
+    if itemType.Kind() != reflect.Struct {
+        return attributes
+    }
+    for _, field := range reflect.VisibleFields(itemType) {
+        if name, ok := attributeName(field); ok {
+            attributes[name] = true
+        }
+    }
+    return attributes
+}
+
+// Atributo em que o attributevalue grava field; ok é false para campos
+// ignorados e para structs embutidas, cujos campos são promovidos
+func attributeName(field reflect.StructField) (name string, ok bool) {
+    if !field.IsExported() || field.Anonymous {
+        return "", false
+    }
+    name, _, _ = strings.Cut(field.Tag.Get("dynamodbav"), ",")
+    switch name {
+    case "-":
+        return "", false
+    case "":
+        return field.Name, true
+    }
+    return name, true
+}
@@ -0,0 +1,166 @@
+package dynamodbstore
+
+import (
+    "fmt"
+    "reflect"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Codificações de tempo escolhidas com `dynamodbstore:"time=<codificação>"`
+// em um campo time.Time. Campos sem a tag mantêm a codificação do
+// attributevalue.
+const (
+    timeRFC3339Nano = "rfc3339nano" // S, precisão de nanossegundos em UTC
+    timeUnix        = "unix"        // N, segundos desde a época Unix
+    timeUnixMilli   = "unixmilli"   // N, milissegundos desde a época Unix
+)
+
+// Sempre com nove dígitos de fração, ao contrário de time.RFC3339Nano, que
+// remove os zeros finais; assim as strings ordenam no tempo. time.RFC3339Nano
+// lê as duas formas.
+const sortableRFC3339Nano = "2006-01-02T15:04:05.000000000Z07:00"
+
+// Codificação de tempo de cada atributo de um tipo de item. Os valores de
+// Filter.Value desses atributos são codificados antes do Scan, para que o
+// DynamoDB compare valores da mesma forma, e os itens são decodificados
+// antes do attributevalue.
+type timeCodec map[string]string
+
+var timeCodecs sync.Map // reflect.Type para timeCodec
+
+func timeCodecOf(itemType reflect.Type) (timeCodec, error) {
+    if cached, ok := timeCodecs.Load(itemType); ok {
+        return cached.(timeCodec), nil
+    }
+
+    codec := make(timeCodec)
+    for _, field := range taggedFields(itemType) {
+        encoding, ok := field.option("time")
+        if !ok {
+            continue
+        }
+        switch encoding {
+        case timeRFC3339Nano, timeUnix, timeUnixMilli:
+        default:
+            return nil, fmt.Errorf("codificação de tempo %q desconhecida no campo %s de %v", encoding, field.name, itemType)
+        }
+        if fieldType := itemType.FieldByIndex(field.index).Type; fieldType != timeType && fieldType != reflect.PointerTo(timeType) {
+            return nil, fmt.Errorf("campo de tempo %s de %v deve ser time.Time, não %v", field.name, itemType, fieldType)
+        }
+        codec[field.attribute] = encoding
+    }
+    timeCodecs.Store(itemType, codec)
+    return codec, nil
+}
+
+func timeCodecFor[T any]() (timeCodec, error) {
+    return timeCodecOf(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Converte value para a codificação de attribute quando é um tempo; os
+// demais valores são retornados sem mudança
+func (c timeCodec) encode(attribute string, value interface{}) interface{} {
+    encoding, ok := c[attribute]
+    if !ok {
+        return value
+    }
+
+    var t time.Time
+    switch v := value.(type) {
+    case time.Time:
+        t = v
+    case *time.Time:
+        if v == nil {
+            return value
+        }
+        t = *v
+    default:
+        return value
+    }
+
+    switch encoding {
+    case timeUnix:
+        return t.Unix()
+    case timeUnixMilli:
+        return t.UnixMilli()
+    }
+    return t.UTC().Format(sortableRFC3339Nano)
+}
+
+// Retorna filters com os valores de tempo codificados
+func (c timeCodec) encodeFilters(filters []Filter) []Filter {
+    if len(c) == 0 || len(filters) == 0 {
+        return filters
+    }
+
+    encoded := make([]Filter, len(filters))
+    for i, filter := range filters {
+        if filter.Combinator != 0 {
+            filter.Filters = c.encodeFilters(filter.Filters)
+        } else if _, ok := c[filter.Name]; ok {
+            filter.Value = c.encodeFilterValue(filter)
+        }
+        encoded[i] = filter
+    }
+    return encoded
+}
+
+func (c timeCodec) encodeFilterValue(filter Filter) interface{} {
+    switch filter.Op {
+    case Between:
+        if bounds, ok := filter.Value.(Range); ok {
+            return Range{Lower: c.encode(filter.Name, bounds.Lower), Upper: c.encode(filter.Name, bounds.Upper)}
+        }
+    case In:
+        values, err := setValues(filter)
+        if err != nil {
+            return filter.Value
+        }
+        for i, value := range values {
+            values[i] = c.encode(filter.Name, value)
+        }
+        return values
+    }
+    return c.encode(filter.Name, filter.Value)
+}
+
+// Converte os atributos de tempo numéricos de record de volta para as
+// strings que o attributevalue decodifica
+func (c timeCodec) decodeRecord(record map[string]types.AttributeValue) error {
+    for attribute, encoding := range c {
+        value, ok := record[attribute].(*types.AttributeValueMemberN)
+        if !ok {
+            continue
+        }
+        n, err := strconv.ParseInt(value.Value, 10, 64)
+        if err != nil {
+            return fmt.Errorf("tempo inválido em %q: %w", attribute, err)
+        }
+
+        t := time.Unix(n, 0)
+        if encoding == timeUnixMilli {
+            t = time.UnixMilli(n)
+        }
+        record[attribute] = &types.AttributeValueMemberS{Value: t.UTC().Format(time.RFC3339Nano)}
+    }
+    return nil
+}
+
+// Decodifica records em uma lista de T
+func unmarshalRecords[T any](c timeCodec, records []map[string]types.AttributeValue) ([]T, error) {
+    for _, record := range records {
+        if err := c.decodeRecord(record); err != nil {
+            return nil, err
+        }
+    }
+    var items []T
+    if err := attributevalue.UnmarshalListOfMaps(records, &items); err != nil {
+        return nil, err
+    }
+    return items, nil
+}
@@ -0,0 +1,119 @@
+package dynamodbstore
+
+import (
+    "context"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+// NodeEvent com o horário gravado como número
+type TimedNodeEvent struct {
+    NodeID    string
+    Timestamp time.Time `dynamodbstore:"time=unixmilli"`
+}
+
+func timedNodeEventPage() *dynamodb.ScanOutput {
+    return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{{
+        "NodeID":    &types.AttributeValueMemberS{Value: "node-1"},
+        "Timestamp": &types.AttributeValueMemberN{Value: "1704067200123"},
+    }}}
+}
+
+func TestTimeCodecOf(t *testing.T) {
+    codec, err := timeCodecOf(reflect.TypeOf(struct {
+        CreatedAt time.Time  `dynamodbstore:"time=unix"`
+        UpdatedAt *time.Time `dynamodbstore:"time=rfc3339nano" dynamodbav:"updated"`
+        DeletedAt time.Time
+    }{}))
+    assert.NoError(t, err)
+    assert.Equal(t, timeCodec{"CreatedAt": timeUnix, "updated": timeRFC3339Nano}, codec)
+
+    for _, itemType := range []reflect.Type{
+        reflect.TypeOf(struct {
+            CreatedAt time.Time `dynamodbstore:"time=iso"`
+        }{}),
+        reflect.TypeOf(struct {
+            CreatedAt int64 `dynamodbstore:"time=unix"`
+        }{}),
+    } {
+        _, err := timeCodecOf(itemType)
+        assert.Error(t, err, "%v", itemType)
+    }
+
+    at := time.Date(2024, 1, 1, 12, 0, 0, 500, time.FixedZone("BRT", -3*60*60))
+    assert.Equal(t, int64(1704121200), timeCodec{"T": timeUnix}.encode("T", at))
+    assert.Equal(t, int64(1704121200000), timeCodec{"T": timeUnixMilli}.encode("T", &at))
+    assert.Equal(t, "2024-01-01T15:00:00.000000500Z", timeCodec{"T": timeRFC3339Nano}.encode("T", at))
+    assert.Equal(t, "2024-01-01", timeCodec{"T": timeUnix}.encode("T", "2024-01-01"))
+}
+
+func TestListItemsEncodesTimes(t *testing.T) {
+    ctx := context.Background()
+    at := time.UnixMilli(1704067200123).UTC()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", mock.Anything, mock.Anything).Return(timedNodeEventPage(), nil)
+
+    filters := []Filter{
+        {Name: "Timestamp", Op: Between, Value: Range{Lower: at.Add(-time.Hour), Upper: at}},
+        Or(
+            Filter{Name: "Timestamp", Op: In, Value: []time.Time{at}},
+            Filter{Name: "NodeID", Op: EqualTo, Value: "node-1"},
+        ),
+    }
+    events, _, err := ListItems[TimedNodeEvent](ctx, "NodeEventsTable", mockClient, filters, nil, nil)
+    if !assert.NoError(t, err) {
+        return
+    }
+
+    // O filtro compara com o mesmo número gravado no item
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput)
+    var numbers []string
+    for _, value := range input.ExpressionAttributeValues {
+        if n, ok := value.(*types.AttributeValueMemberN); ok {
+            numbers = append(numbers, n.Value)
+        }
+    }
+    assert.ElementsMatch(t, []string{"1704063600123", "1704067200123", "1704067200123"}, numbers)
+
+    if assert.Len(t, events, 1) {
+        assert.True(t, at.Equal(events[0].Timestamp), "recebeu %v", events[0].Timestamp)
+    }
+
+    // A variante sob demanda e o scan paralelo decodificam da mesma forma
+    for event, err := range ListItemsIter[TimedNodeEvent](ctx, "NodeEventsTable", mockClient, filters, nil) {
+        if assert.NoError(t, err) {
+            assert.True(t, at.Equal(event.Timestamp), "recebeu %v", event.Timestamp)
+        }
+    }
+    events, _, err = ListItemsParallel[TimedNodeEvent](ctx, "NodeEventsTable", mockClient, filters, nil, nil, 1, 1)
+    if assert.NoError(t, err) && assert.Len(t, events, 1) {
+        assert.True(t, at.Equal(events[0].Timestamp), "recebeu %v", events[0].Timestamp)
+    }
+}
+
+func TestCountItemsOfEncodesTimes(t *testing.T) {
+    ctx := context.Background()
+    at := time.UnixMilli(1704067200123).UTC()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{Count: 1, ScannedCount: 1}, nil).Once()
+
+    filters := []Filter{{Name: "Timestamp", Op: LessThan, Value: at}}
+    count, err := CountItemsOf[TimedNodeEvent](ctx, "NodeEventsTable", mockClient, filters)
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Equal(t, ItemCount{Count: 1, ScannedCount: 1}, count)
+
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput)
+    if assert.Len(t, input.ExpressionAttributeValues, 1) {
+        for _, value := range input.ExpressionAttributeValues {
+            assert.Equal(t, &types.AttributeValueMemberN{Value: "1704067200123"}, value)
+        }
+    }
+}
@@ -0,0 +1,361 @@
+package dynamodbstore
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "hash"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Corresponde a todo erro de um Pagination.Token inutilizável; use
+// errors.As com *TokenError para saber o motivo
+var ErrInvalidToken = errors.New("token de paginação inválido")
+
+// Motivo da rejeição de um token de paginação
+type TokenErrorReason int
+
+const (
+    TokenMalformed TokenErrorReason = iota + 1
+    TokenBadSignature
+    TokenExpired
+    TokenWrongTable
+    TokenWrongQuery
+)
+
+func (r TokenErrorReason) String() string {
+    switch r {
+    case TokenMalformed:
+        return "malformado"
+    case TokenBadSignature:
+        return "assinatura inválida"
+    case TokenExpired:
+        return "expirado"
+    case TokenWrongTable:
+        return "emitido para outra tabela"
+    case TokenWrongQuery:
+        return "emitido para outra consulta (filtros, condição de chave ou projeção diferentes)"
+    }
+    return fmt.Sprintf("motivo %d", int(r))
+}
+
+// Erro retornado quando o Pagination.Token é rejeitado
+type TokenError struct {
+    Reason TokenErrorReason
+    Err    error
+}
+
+func (e *TokenError) Error() string {
+    if e.Err != nil {
+        return fmt.Sprintf("%v: %v: %v", ErrInvalidToken, e.Reason, e.Err)
+    }
+    return fmt.Sprintf("%v: %v", ErrInvalidToken, e.Reason)
+}
+
+func (e *TokenError) Unwrap() error { return e.Err }
+
+func (e *TokenError) Is(target error) bool { return target == ErrInvalidToken }
+
+// Segredo HMAC de um TokenSigner, identificado por ID para que tokens
+// assinados antes de uma rotação continuem verificáveis
+type SigningKey struct {
+    ID     string
+    Secret []byte
+}
+
+// Autentica os tokens de paginação com HMAC-SHA256. Keys[0] assina os novos
+// tokens e todas as chaves de Keys são aceitas na verificação: para rotacionar,
+// adicione a nova chave no início e remova a antiga quando seus tokens
+// expirarem. TTL zero emite tokens que não expiram.
+type TokenSigner struct {
+    Keys []SigningKey
+    TTL  time.Duration
+
+    now func() time.Time
+}
+
+func (s *TokenSigner) clock() time.Time {
+    if s.now != nil {
+        return s.now()
+    }
+    return time.Now()
+}
+
+func (s *TokenSigner) key(id string) []byte {
+    for _, key := range s.Keys {
+        if key.ID == id {
+            return key.Secret
+        }
+    }
+    return nil
+}
+
+func (s *TokenSigner) mac(secret []byte, payload string) string {
+    h := hmac.New(sha256.New, secret)
+    h.Write([]byte(payload))
+    return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Versão do formato do token; tokens de outra versão são rejeitados em vez
+// de interpretados de forma errada
+const tokenVersion = 1
+
+// Conteúdo do Pagination.NextToken. Guarda todos os atributos do
+// LastEvaluatedKey, inclusive chaves de ordenação e de índices; o scan
+// paralelo guarda a posição de cada segmento em Segments. Tokens assinados
+// terminam com "." e o MAC do conteúdo codificado.
+type pageToken struct {
+    Version  int                       `json:"v"`
+    Table    string                    `json:"t"`
+    Query    string                    `json:"q"`
+    Key      map[string]tokenAttribute `json:"k,omitempty"`
+    Segments []segmentToken            `json:"seg,omitempty"`
+    KeyID    string                    `json:"kid,omitempty"`
+    Expires  int64                     `json:"exp,omitempty"`
+}
+
+// Um atributo da chave; chaves só podem ser S, N ou B
+type tokenAttribute struct {
+    S *string `json:"s,omitempty"`
+    N *string `json:"n,omitempty"`
+    B []byte  `json:"b,omitempty"`
+}
+
+// Posição de um segmento do scan paralelo
+type segmentToken struct {
+    Key  map[string]tokenAttribute `json:"k,omitempty"`
+    Done bool                      `json:"d,omitempty"`
+}
+
+func encodeToken(table, fingerprint string, key map[string]types.AttributeValue, signer *TokenSigner) (string, error) {
+    attributes, err := encodeKey(key)
+    if err != nil {
+        return "", err
+    }
+    return sealToken(pageToken{Table: table, Query: fingerprint, Key: attributes}, signer)
+}
+
+func decodeToken(table, fingerprint, encoded string, signer *TokenSigner) (map[string]types.AttributeValue, error) {
+    token, err := openToken(table, fingerprint, encoded, signer)
+    if err != nil {
+        return nil, err
+    }
+    if len(token.Key) == 0 {
+        return nil, &TokenError{Reason: TokenMalformed, Err: errors.New("chave vazia")}
+    }
+    return decodeKey(token.Key)
+}
+
+func encodeKey(key map[string]types.AttributeValue) (map[string]tokenAttribute, error) {
+    attributes := make(map[string]tokenAttribute, len(key))
+    for name, value := range key {
+        switch v := value.(type) {
+        case *types.AttributeValueMemberS:
+            attributes[name] = tokenAttribute{S: &v.Value}
+        case *types.AttributeValueMemberN:
+            attributes[name] = tokenAttribute{N: &v.Value}
+        case *types.AttributeValueMemberB:
+            attributes[name] = tokenAttribute{B: v.Value}
+        default:
+            return nil, fmt.Errorf("atributo de chave %q com tipo não suportado %T", name, value)
+        }
+    }
+    return attributes, nil
+}
+
+func decodeKey(attributes map[string]tokenAttribute) (map[string]types.AttributeValue, error) {
+    key := make(map[string]types.AttributeValue, len(attributes))
+    for name, value := range attributes {
+        switch {
+        case value.S != nil && value.N == nil && value.B == nil:
+            key[name] = &types.AttributeValueMemberS{Value: *value.S}
+        case value.N != nil && value.S == nil && value.B == nil:
+            key[name] = &types.AttributeValueMemberN{Value: *value.N}
+        case value.B != nil && value.S == nil && value.N == nil:
+            key[name] = &types.AttributeValueMemberB{Value: value.B}
+        default:
+            return nil, &TokenError{Reason: TokenMalformed, Err: fmt.Errorf("atributo %q malformado", name)}
+        }
+    }
+    return key, nil
+}
+
+// Serializa o token e, com signer, acrescenta a assinatura e a validade
+func sealToken(token pageToken, signer *TokenSigner) (string, error) {
+    token.Version = tokenVersion
+    if signer != nil {
+        if len(signer.Keys) == 0 {
+            return "", errors.New("token signer sem chaves")
+        }
+        token.KeyID = signer.Keys[0].ID
+        if signer.TTL > 0 {
+            token.Expires = signer.clock().Add(signer.TTL).Unix()
+        }
+    }
+
+    data, err := json.Marshal(token)
+    if err != nil {
+        return "", err
+    }
+    payload := base64.RawURLEncoding.EncodeToString(data)
+    if signer == nil {
+        return payload, nil
+    }
+    return payload + "." + signer.mac(signer.Keys[0].Secret, payload), nil
+}
+
+// Decodifica o token e confere assinatura, validade, tabela e consulta
+func openToken(table, fingerprint, encoded string, signer *TokenSigner) (pageToken, error) {
+    payload, signature, signed := strings.Cut(encoded, ".")
+
+    data, err := base64.RawURLEncoding.DecodeString(payload)
+    if err != nil {
+        return pageToken{}, &TokenError{Reason: TokenMalformed, Err: err}
+    }
+
+    var token pageToken
+    if err := json.Unmarshal(data, &token); err != nil {
+        return pageToken{}, &TokenError{Reason: TokenMalformed, Err: err}
+    }
+    if token.Version != tokenVersion {
+        return pageToken{}, &TokenError{Reason: TokenMalformed, Err: fmt.Errorf("versão %d não suportada", token.Version)}
+    }
+
+    // Nada no conteúdo é confiável antes de verificar a assinatura
+    if signer != nil {
+        secret := signer.key(token.KeyID)
+        if !signed || secret == nil || !hmac.Equal([]byte(signature), []byte(signer.mac(secret, payload))) {
+            return pageToken{}, &TokenError{Reason: TokenBadSignature}
+        }
+        if token.Expires != 0 && !signer.clock().Before(time.Unix(token.Expires, 0)) {
+            return pageToken{}, &TokenError{Reason: TokenExpired}
+        }
+    }
+
+    if token.Table != table {
+        return pageToken{}, &TokenError{Reason: TokenWrongTable, Err: fmt.Errorf("token é da tabela %q, não de %q", token.Table, table)}
+    }
+    if token.Query != fingerprint {
+        return pageToken{}, &TokenError{Reason: TokenWrongQuery}
+    }
+    return token, nil
+}
+
+// Extrai do item os atributos presentes em um LastEvaluatedKey, para que
+// uma página cortada no item seja retomada logo depois dele
+func itemKey(item, lastEvaluatedKey map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+    if len(lastEvaluatedKey) == 0 {
+        return nil, errors.New("nenhuma chave conhecida para retomar após o último item")
+    }
+    key := make(map[string]types.AttributeValue, len(lastEvaluatedKey))
+    for name := range lastEvaluatedKey {
+        value, ok := item[name]
+        if !ok {
+            return nil, fmt.Errorf("atributo de chave %q ausente do item; inclua-o na projeção", name)
+        }
+        key[name] = value
+    }
+    return key, nil
+}
+
+// Hash de tudo que decide quais itens a requisição retorna, para que o token
+// só seja aceito pela mesma consulta que o emitiu. De ExcludeExpired entra o
+// atributo da expiração, não o instante usado no filtro.
+func queryFingerprint(table string, expr expression.Expression, expiry string) string {
+    h := sha256.New()
+    writeString(h, table)
+    if expiry != "" {
+        writeString(h, "unexpired:"+expiry)
+    }
+    for _, part := range []*string{expr.KeyCondition(), expr.Filter(), expr.Projection()} {
+        if part == nil {
+            writeString(h, "")
+            continue
+        }
+        writeString(h, *part)
+    }
+
+    names := expr.Names()
+    for _, placeholder := range sortedKeys(names) {
+        writeString(h, placeholder)
+        writeString(h, names[placeholder])
+    }
+    values := expr.Values()
+    for _, placeholder := range sortedKeys(values) {
+        writeString(h, placeholder)
+        writeValue(h, values[placeholder])
+    }
+    return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16])
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+// Prefixa o tamanho para que campos vizinhos não se confundam
+func writeString(h hash.Hash, s string) {
+    fmt.Fprintf(h, "%d:%s", len(s), s)
+}
+
+func writeValue(h hash.Hash, value types.AttributeValue) {
+    switch v := value.(type) {
+    case *types.AttributeValueMemberS:
+        writeString(h, "S")
+        writeString(h, v.Value)
+    case *types.AttributeValueMemberN:
+        writeString(h, "N")
+        writeString(h, v.Value)
+    case *types.AttributeValueMemberB:
+        writeString(h, "B")
+        writeString(h, string(v.Value))
+    case *types.AttributeValueMemberBOOL:
+        writeString(h, "BOOL")
+        writeString(h, fmt.Sprint(v.Value))
+    case *types.AttributeValueMemberNULL:
+        writeString(h, "NULL")
+    case *types.AttributeValueMemberSS:
+        writeString(h, "SS")
+        writeStrings(h, v.Value)
+    case *types.AttributeValueMemberNS:
+        writeString(h, "NS")
+        writeStrings(h, v.Value)
+    case *types.AttributeValueMemberBS:
+        writeString(h, "BS")
+        for _, b := range v.Value {
+            writeString(h, string(b))
+        }
+    case *types.AttributeValueMemberL:
+        writeString(h, fmt.Sprintf("L%d", len(v.Value)))
+        for _, item := range v.Value {
+            writeValue(h, item)
+        }
+    case *types.AttributeValueMemberM:
+        writeString(h, fmt.Sprintf("M%d", len(v.Value)))
+        for _, name := range sortedKeys(v.Value) {
+            writeString(h, name)
+            writeValue(h, v.Value[name])
+        }
+    default:
+        writeString(h, fmt.Sprintf("%T", value))
+    }
+}
+
+func writeStrings(h hash.Hash, values []string) {
+    writeString(h, fmt.Sprint(len(values)))
+    for _, value := range values {
+        writeString(h, value)
+    }
+}
@@ -0,0 +1,267 @@
+package dynamodbstore
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+func TestTokenRoundTrip(t *testing.T) {
+    key := map[string]types.AttributeValue{
+        "TrustDomain": &types.AttributeValueMemberS{Value: "example.org"},
+        "EventID":     &types.AttributeValueMemberN{Value: "42"},
+        "Digest":      &types.AttributeValueMemberB{Value: []byte{0x00, 0xff, 0x10}},
+    }
+
+    token, err := encodeToken("EntriesTable", "", key, nil)
+    assert.NoError(t, err)
+
+    decoded, err := decodeToken("EntriesTable", "", token, nil)
+    assert.NoError(t, err)
+    assert.Equal(t, key, decoded)
+}
+
+func TestDecodeTokenRejectsInvalidTokens(t *testing.T) {
+    for _, token := range []string{
+        "not base64!",
+        "bm90IGpzb24",                                          // "não é json"
+        "eyJ2IjoyLCJrIjp7IkEiOnsicyI6ImEifX19",                 // versão 2
+        "eyJ2IjoxLCJ0IjoiRW50cmllc1RhYmxlIiwiayI6e319",         // chave vazia
+        "eyJ2IjoxLCJ0IjoiRW50cmllc1RhYmxlIiwiayI6eyJBIjp7fX19", // atributo sem membro
+    } {
+        _, err := decodeToken("EntriesTable", "", token, nil)
+        assert.ErrorIs(t, err, ErrInvalidToken, token)
+    }
+}
+
+func TestEncodeTokenRejectsNonKeyAttributes(t *testing.T) {
+    _, err := encodeToken("EntriesTable", "", map[string]types.AttributeValue{
+        "Selectors": &types.AttributeValueMemberSS{Value: []string{"a"}},
+    }, nil)
+    assert.Error(t, err)
+}
+
+func TestListItemsResumesFromFullKey(t *testing.T) {
+    ctx := context.Background()
+    lastKey := map[string]types.AttributeValue{
+        "TrustDomain": &types.AttributeValueMemberS{Value: "example.org"},
+        "EventID":     &types.AttributeValueMemberN{Value: "7"},
+        "GSI1PK":      &types.AttributeValueMemberS{Value: "BUNDLE"},
+    }
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{LastEvaluatedKey: lastKey}, nil).Once()
+
+    pagination := &Pagination{Limit: 10}
+    _, pagination, err := ListItems[FederationRelationship](ctx, "FederationRelationshipsTable", mockClient, nil, pagination, nil)
+    assert.NoError(t, err)
+    assert.NotEmpty(t, pagination.NextToken)
+
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{}, nil).Once()
+    pagination.Token = pagination.NextToken
+    _, pagination, err = ListItems[FederationRelationship](ctx, "FederationRelationshipsTable", mockClient, nil, pagination, nil)
+    assert.NoError(t, err)
+    assert.Empty(t, pagination.NextToken)
+
+    input := mockClient.Calls[1].Arguments.Get(1).(*dynamodb.ScanInput)
+    assert.Equal(t, lastKey, input.ExclusiveStartKey)
+
+    // Sem filtros nem projeção o Scan não leva expressões
+    assert.Nil(t, input.FilterExpression)
+    assert.Nil(t, input.ProjectionExpression)
+    assert.Empty(t, input.ExpressionAttributeNames)
+    assert.Empty(t, input.ExpressionAttributeValues)
+}
+
+func TestListItemsRejectsInvalidToken(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+
+    _, _, err := ListItems[FederationRelationship](ctx, "FederationRelationshipsTable", mockClient, nil, &Pagination{Token: "example.org"}, nil)
+    assert.ErrorIs(t, err, ErrInvalidToken)
+    mockClient.AssertNotCalled(t, "Scan", mock.Anything, mock.Anything)
+}
+
+func TestSignedTokens(t *testing.T) {
+    key := map[string]types.AttributeValue{"SpiffeID": &types.AttributeValueMemberS{Value: "spiffe://example.org/node"}}
+    now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    signer := &TokenSigner{
+        Keys: []SigningKey{{ID: "k1", Secret: []byte("first secret")}},
+        TTL:  time.Hour,
+        now:  func() time.Time { return now },
+    }
+
+    token, err := encodeToken("EntriesTable", "", key, signer)
+    assert.NoError(t, err)
+
+    decoded, err := decodeToken("EntriesTable", "", token, signer)
+    assert.NoError(t, err)
+    assert.Equal(t, key, decoded)
+
+    unsigned, err := encodeToken("EntriesTable", "", key, nil)
+    assert.NoError(t, err)
+    forged, err := encodeToken("EntriesTable", "", key, &TokenSigner{Keys: []SigningKey{{ID: "k1", Secret: []byte("guessed")}}})
+    assert.NoError(t, err)
+    other, err := encodeToken("BundlesTable", "", key, signer)
+    assert.NoError(t, err)
+
+    // Troca o conteúdo de um token válido pelo de outra chave, mantendo o MAC
+    moved, err := encodeToken("EntriesTable", "", map[string]types.AttributeValue{"SpiffeID": &types.AttributeValueMemberS{Value: "spiffe://example.org/other"}}, signer)
+    assert.NoError(t, err)
+    payload, _, _ := strings.Cut(moved, ".")
+    _, signature, _ := strings.Cut(token, ".")
+    tampered := payload + "." + signature
+
+    rotated := &TokenSigner{
+        Keys: []SigningKey{{ID: "k2", Secret: []byte("second secret")}, signer.Keys[0]},
+        TTL:  time.Hour,
+        now:  signer.now,
+    }
+    decoded, err = decodeToken("EntriesTable", "", token, rotated)
+    assert.NoError(t, err)
+    assert.Equal(t, key, decoded)
+
+    retired := &TokenSigner{Keys: rotated.Keys[:1], now: signer.now}
+    expired := &TokenSigner{Keys: signer.Keys, now: func() time.Time { return now.Add(2 * time.Hour) }}
+
+    cases := []struct {
+        token  string
+        signer *TokenSigner
+        reason TokenErrorReason
+    }{
+        {unsigned, signer, TokenBadSignature},
+        {forged, signer, TokenBadSignature},
+        {tampered, signer, TokenBadSignature},
+        {token, retired, TokenBadSignature},
+        {token, expired, TokenExpired},
+        {other, signer, TokenWrongTable},
+    }
+    for _, c := range cases {
+        _, err := decodeToken("EntriesTable", "", c.token, c.signer)
+        var tokenErr *TokenError
+        if assert.True(t, errors.As(err, &tokenErr), "%v", err) {
+            assert.Equal(t, c.reason, tokenErr.Reason)
+        }
+        assert.ErrorIs(t, err, ErrInvalidToken)
+    }
+}
+
+func TestListItemsRejectsTokenFromAnotherQuery(t *testing.T) {
+    ctx := context.Background()
+    lastKey := map[string]types.AttributeValue{"SpiffeID": &types.AttributeValueMemberS{Value: "spiffe://example.org/node"}}
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{LastEvaluatedKey: lastKey}, nil)
+
+    filters := []Filter{{Name: "ParentID", Op: EqualTo, Value: "spiffe://example.org/parent"}}
+    _, pagination, err := ListItems[Entry](ctx, "EntriesTable", mockClient, filters, &Pagination{Limit: 10}, nil)
+    assert.NoError(t, err)
+    token := pagination.NextToken
+
+    var tokenErr *TokenError
+    other := []Filter{{Name: "ParentID", Op: EqualTo, Value: "spiffe://example.org/other"}}
+    _, _, err = ListItems[Entry](ctx, "EntriesTable", mockClient, other, &Pagination{Token: token}, nil)
+    if assert.True(t, errors.As(err, &tokenErr), "%v", err) {
+        assert.Equal(t, TokenWrongQuery, tokenErr.Reason)
+    }
+
+    _, _, err = ListItems[Entry](ctx, "EntriesTable", mockClient, filters, &Pagination{Token: token}, []string{"SpiffeID"})
+    if assert.True(t, errors.As(err, &tokenErr), "%v", err) {
+        assert.Equal(t, TokenWrongQuery, tokenErr.Reason)
+    }
+
+    _, _, err = ListItems[Entry](ctx, "OtherEntriesTable", mockClient, filters, &Pagination{Token: token}, nil)
+    if assert.True(t, errors.As(err, &tokenErr), "%v", err) {
+        assert.Equal(t, TokenWrongTable, tokenErr.Reason)
+    }
+
+    _, _, err = ListItems[Entry](ctx, "EntriesTable", mockClient, filters, &Pagination{Token: token}, nil)
+    assert.NoError(t, err)
+}
+
+func TestListItemsFillsPageToLimit(t *testing.T) {
+    ctx := context.Background()
+    entry := func(id string) map[string]types.AttributeValue {
+        return map[string]types.AttributeValue{
+            "SpiffeID": &types.AttributeValueMemberS{Value: id},
+            "ParentID": &types.AttributeValueMemberS{Value: "spiffe://example.org/parent"},
+        }
+    }
+    key := func(id string) map[string]types.AttributeValue {
+        return map[string]types.AttributeValue{"SpiffeID": &types.AttributeValueMemberS{Value: id}}
+    }
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{entry("a")}, LastEvaluatedKey: key("b")}, nil).Once()
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{entry("c"), entry("d"), entry("e")}, LastEvaluatedKey: key("e")}, nil).Once()
+
+    pagination := &Pagination{Limit: 3, Fill: true}
+    results, pagination, err := ListItems[Entry](ctx, "EntriesTable", mockClient, nil, pagination, nil)
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Equal(t, []Entry{
+        {SpiffeID: "a", ParentID: "spiffe://example.org/parent"},
+        {SpiffeID: "c", ParentID: "spiffe://example.org/parent"},
+        {SpiffeID: "d", ParentID: "spiffe://example.org/parent"},
+    }, results)
+    assert.NotEmpty(t, pagination.NextToken)
+
+    // Retoma logo após o último item retornado, não após o último avaliado
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{entry("e")}}, nil).Once()
+    pagination.Token = pagination.NextToken
+    results, pagination, err = ListItems[Entry](ctx, "EntriesTable", mockClient, nil, pagination, nil)
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Len(t, results, 1)
+    assert.Empty(t, pagination.NextToken)
+
+    assert.Equal(t, key("b"), mockClient.Calls[1].Arguments.Get(1).(*dynamodb.ScanInput).ExclusiveStartKey)
+    assert.Equal(t, key("d"), mockClient.Calls[2].Arguments.Get(1).(*dynamodb.ScanInput).ExclusiveStartKey)
+}
+
+func TestListItemsFillAddsKeyToProjection(t *testing.T) {
+    ctx := context.Background()
+    entry := func(id string) map[string]types.AttributeValue {
+        return map[string]types.AttributeValue{
+            "SpiffeID": &types.AttributeValueMemberS{Value: id},
+            "ParentID": &types.AttributeValueMemberS{Value: "spiffe://example.org/parent"},
+        }
+    }
+    parent := map[string]types.AttributeValue{"ParentID": &types.AttributeValueMemberS{Value: "spiffe://example.org/parent"}}
+
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{
+        Items:            []map[string]types.AttributeValue{parent},
+        LastEvaluatedKey: map[string]types.AttributeValue{"SpiffeID": &types.AttributeValueMemberS{Value: "a"}},
+    }, nil).Once()
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{entry("b"), entry("c")}}, nil).Once()
+
+    results, pagination, err := ListItems[Entry](ctx, "EntriesTable", mockClient, nil, &Pagination{Limit: 2, Fill: true}, []string{"ParentID"})
+    if !assert.NoError(t, err) {
+        return
+    }
+
+    // A chave é lida a partir da segunda página e removida dos itens
+    assert.Equal(t, []Entry{{ParentID: "spiffe://example.org/parent"}, {ParentID: "spiffe://example.org/parent"}}, results)
+    first := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput)
+    second := mockClient.Calls[1].Arguments.Get(1).(*dynamodb.ScanInput)
+    assert.ElementsMatch(t, []string{"ParentID"}, attributeNames(first))
+    assert.ElementsMatch(t, []string{"ParentID", "SpiffeID"}, attributeNames(second))
+
+    // O token retoma após o último item retornado e vale para a projeção pedida
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{}, nil).Once()
+    pagination.Token = pagination.NextToken
+    _, _, err = ListItems[Entry](ctx, "EntriesTable", mockClient, nil, pagination, []string{"ParentID"})
+    assert.NoError(t, err)
+    startKey := mockClient.Calls[2].Arguments.Get(1).(*dynamodb.ScanInput).ExclusiveStartKey
+    assert.Equal(t, map[string]types.AttributeValue{"SpiffeID": &types.AttributeValueMemberS{Value: "b"}}, startKey)
+}
@@ -0,0 +1,75 @@
+package dynamodbstore
+
+import (
+    "fmt"
+    "reflect"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
+
+// Atributo da expiração de um campo `dynamodbstore:"ttl"` sem nome definido
+const defaultTTLAttribute = "TTL"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Atributo numérico com a expiração em segundos Unix do campo time.Time com a
+// tag `dynamodbstore:"ttl"`, o mesmo que o Time to Live do DynamoDB lê. É
+// "TTL", a menos que a tag dê outro nome, como em
+// `dynamodbstore:"ttl=ExpiresAtEpoch"`. Retorna "" se itemType não tem esse
+// campo.
+func ttlAttribute(itemType reflect.Type) (string, error) {
+    var epoch string
+    for _, field := range taggedFields(itemType) {
+        name, ok := field.option("ttl")
+        if !ok {
+            continue
+        }
+        if epoch != "" {
+            return "", fmt.Errorf("%v tem mais de um campo ttl", itemType)
+        }
+        if fieldType := itemType.FieldByIndex(field.index).Type; fieldType != timeType && fieldType != reflect.PointerTo(timeType) {
+            return "", fmt.Errorf("campo ttl %s de %v deve ser time.Time, não %v", field.name, itemType, fieldType)
+        }
+        if name == "" {
+            name = defaultTTLAttribute
+        }
+        if itemAttributes(itemType)[name] {
+            return "", fmt.Errorf("atributo ttl %q de %v já é um de seus campos", name, itemType)
+        }
+        epoch = name
+    }
+    return epoch, nil
+}
+
+// Descarta os itens cujo campo ttl já passou, que o DynamoDB ainda pode
+// retornar por um tempo antes de removê-los. O tipo do item precisa de um
+// campo com a tag `dynamodbstore:"ttl"`.
+func ExcludeExpired() Option {
+    return func(o *options) {
+        o.excludeExpired = true
+    }
+}
+
+// Resolve ExcludeExpired com o campo ttl de T
+func withExpiry[T any](settings options) (options, error) {
+    if !settings.excludeExpired {
+        return settings, nil
+    }
+    itemType := reflect.TypeOf((*T)(nil)).Elem()
+    epoch, err := ttlAttribute(itemType)
+    if err != nil {
+        return settings, err
+    }
+    if epoch == "" {
+        return settings, fmt.Errorf("%v não tem campo com a tag %s:\"ttl\"", itemType, tagName)
+    }
+    settings.expiry = epoch
+    return settings, nil
+}
+
+// Vale para itens sem expiração ou que expiram depois de now
+func unexpiredCondition(attribute string, now time.Time) expression.ConditionBuilder {
+    epoch := expression.Name(attribute)
+    return expression.Or(epoch.AttributeNotExists(), epoch.GreaterThan(expression.Value(now.Unix())))
+}
@@ -0,0 +1,101 @@
+package dynamodbstore
+
+import (
+    "context"
+    "reflect"
+    "strconv"
+    "testing"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+    "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/mock"
+)
+
+// JoinToken com a expiração gravada também em segundos Unix
+type ExpiringJoinToken struct {
+    Token     string
+    ExpiresAt time.Time `dynamodbstore:"ttl"`
+}
+
+func TestTTLAttribute(t *testing.T) {
+    epoch, err := ttlAttribute(reflect.TypeOf(ExpiringJoinToken{}))
+    assert.NoError(t, err)
+    assert.Equal(t, "TTL", epoch)
+
+    epoch, err = ttlAttribute(reflect.TypeOf(struct {
+        ExpiresAt *time.Time `dynamodbstore:"ttl=ExpiresAtEpoch"`
+    }{}))
+    assert.NoError(t, err)
+    assert.Equal(t, "ExpiresAtEpoch", epoch)
+
+    epoch, err = ttlAttribute(reflect.TypeOf(JoinToken{}))
+    assert.NoError(t, err)
+    assert.Empty(t, epoch)
+
+    for _, itemType := range []reflect.Type{
+        reflect.TypeOf(struct {
+            ExpiresAt int64 `dynamodbstore:"ttl"`
+        }{}),
+        reflect.TypeOf(struct {
+            CreatedAt time.Time `dynamodbstore:"ttl"`
+            ExpiresAt time.Time `dynamodbstore:"ttl"`
+        }{}),
+        reflect.TypeOf(struct {
+            ExpiresAt time.Time `dynamodbstore:"ttl=Token"`
+            Token     string
+        }{}),
+    } {
+        _, err := ttlAttribute(itemType)
+        assert.Error(t, err, "%v", itemType)
+    }
+}
+
+func TestListItemsExcludeExpired(t *testing.T) {
+    ctx := context.Background()
+    mockClient := new(MockDynamoDBClient)
+    mockClient.On("Scan", ctx, mock.Anything).Return(&dynamodb.ScanOutput{
+        Items:            []map[string]types.AttributeValue{{"Token": &types.AttributeValueMemberS{Value: "a"}}},
+        LastEvaluatedKey: map[string]types.AttributeValue{"Token": &types.AttributeValueMemberS{Value: "a"}},
+    }, nil)
+
+    before := time.Now().Unix()
+    pagination := &Pagination{Limit: 1}
+    results, pagination, err := ListItems[ExpiringJoinToken](ctx, "JoinTokensTable", mockClient, nil, pagination, nil, ExcludeExpired())
+    if !assert.NoError(t, err) {
+        return
+    }
+    assert.Equal(t, []ExpiringJoinToken{{Token: "a"}}, results)
+
+    // Sem filtros, o Scan leva apenas a condição de expiração
+    input := mockClient.Calls[0].Arguments.Get(1).(*dynamodb.ScanInput)
+    assert.Contains(t, *input.FilterExpression, "attribute_not_exists")
+    assert.Equal(t, []string{"TTL"}, attributeNames(input))
+    for _, value := range input.ExpressionAttributeValues {
+        now, err := strconv.ParseInt(value.(*types.AttributeValueMemberN).Value, 10, 64)
+        assert.NoError(t, err)
+        assert.GreaterOrEqual(t, now, before)
+    }
+
+    // A próxima página é aceita mesmo com o relógio adiantado
+    pagination = &Pagination{Limit: 1, Token: pagination.NextToken}
+    _, _, err = ListItems[ExpiringJoinToken](ctx, "JoinTokensTable", mockClient, nil, pagination, nil, ExcludeExpired())
+    assert.NoError(t, err)
+
+    // Sem a opção o token pertence a outra consulta
+    _, _, err = ListItems[ExpiringJoinToken](ctx, "JoinTokensTable", mockClient, nil, pagination, nil)
+    assert.ErrorIs(t, err, ErrInvalidToken)
+
+    // Com filtros as duas condições são combinadas
+    filters := []Filter{{Name: "Token", Op: BeginsWith, Value: "a"}}
+    _, _, err = ListItems[ExpiringJoinToken](ctx, "JoinTokensTable", mockClient, filters, &Pagination{Limit: 1}, nil, ExcludeExpired())
+    assert.NoError(t, err)
+    input = mockClient.Calls[2].Arguments.Get(1).(*dynamodb.ScanInput)
+    assert.Contains(t, *input.FilterExpression, "begins_with")
+    assert.Contains(t, *input.FilterExpression, "attribute_not_exists")
+
+    _, _, err = ListItems[JoinToken](ctx, "JoinTokensTable", mockClient, nil, nil, nil, ExcludeExpired())
+    assert.Error(t, err)
+    mockClient.AssertNumberOfCalls(t, "Scan", 3)
+}